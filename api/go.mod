@@ -2,22 +2,11 @@ module github.com/TheRangiCrew/NWWS-GO/api
 
 go 1.21.6
 
+require github.com/TheRangiCrew/NWWS-GO/parser v0.0.0-00010101000000-000000000000
+
 require (
-	github.com/Microsoft/go-winio v0.4.14 // indirect
-	github.com/distribution/reference v0.5.0 // indirect
-	github.com/docker/docker v25.0.1+incompatible // indirect
-	github.com/docker/go-connections v0.5.0 // indirect
-	github.com/docker/go-units v0.5.0 // indirect
-	github.com/felixge/httpsnoop v1.0.4 // indirect
-	github.com/go-logr/logr v1.4.1 // indirect
-	github.com/go-logr/stdr v1.2.2 // indirect
-	github.com/gogo/protobuf v1.3.2 // indirect
-	github.com/opencontainers/go-digest v1.0.0 // indirect
-	github.com/opencontainers/image-spec v1.0.2 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.47.0 // indirect
-	go.opentelemetry.io/otel v1.22.0 // indirect
-	go.opentelemetry.io/otel/metric v1.22.0 // indirect
-	go.opentelemetry.io/otel/trace v1.22.0 // indirect
-	golang.org/x/sys v0.1.0 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/surrealdb/surrealdb.go v0.2.2-0.20240205063555-7c2584a964ab // indirect
 )
+
+replace github.com/TheRangiCrew/NWWS-GO/parser => ../parser
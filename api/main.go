@@ -1,16 +1,320 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/db"
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
 )
 
 func first(w http.ResponseWriter, r *http.Request) {
 	io.WriteString(w, "Hello there")
 }
 
+// Warning verification for ?start=YYYY-MM-DD&end=YYYY-MM-DD&period=all|day|month|year
+func verification(w http.ResponseWriter, r *http.Request) {
+	dateLayout := "2006-01-02"
+	query := r.URL.Query()
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := query.Get("end"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "end is not a valid date", http.StatusBadRequest)
+			return
+		}
+		end = t
+	}
+
+	start := end.Add(-30 * 24 * time.Hour)
+	if value := query.Get("start"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "start is not a valid date", http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+
+	if !start.Before(end) {
+		http.Error(w, "start must be before end", http.StatusBadRequest)
+		return
+	}
+
+	period := query.Get("period")
+	switch period {
+	case "":
+		period = parsers.VerificationPeriodAll
+	case parsers.VerificationPeriodAll, parsers.VerificationPeriodDay, parsers.VerificationPeriodMonth, parsers.VerificationPeriodYear:
+	default:
+		http.Error(w, "period is not valid", http.StatusBadRequest)
+		return
+	}
+
+	results, err := db.QueryVerification(start, end, period)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to verify warnings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// Text product search for ?wfo=TOP&awips=AFDTOP&start=YYYY-MM-DD&end=YYYY-MM-DD&q=words&limit=N
+func products(w http.ResponseWriter, r *http.Request) {
+	dateLayout := "2006-01-02"
+	query := r.URL.Query()
+
+	search := db.TextProductQuery{
+		WFO:   query.Get("wfo"),
+		AWIPS: query.Get("awips"),
+		Text:  query.Get("q"),
+	}
+	if value := query.Get("start"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "start is not a valid date", http.StatusBadRequest)
+			return
+		}
+		search.Start = t
+	}
+	if value := query.Get("end"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "end is not a valid date", http.StatusBadRequest)
+			return
+		}
+		search.End = t
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "limit is not a number", http.StatusBadRequest)
+			return
+		}
+		search.Limit = limit
+	}
+
+	if search.WFO == "" && search.AWIPS == "" && search.Text == "" && search.Start.IsZero() && search.End.IsZero() {
+		http.Error(w, "give at least one of wfo, awips, start, end or q", http.StatusBadRequest)
+		return
+	}
+
+	results, err := db.SearchTextProducts(search)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to search products", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// The SPC outlook covering a point for ?type=convective|fire&day=N&lat=N&lon=N&time=RFC3339
+func outlooks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	outlookType := query.Get("type")
+	switch outlookType {
+	case "":
+		outlookType = parsers.OutlookConvective
+	case parsers.OutlookConvective, parsers.OutlookFireWeather:
+	default:
+		http.Error(w, "type is not valid", http.StatusBadRequest)
+		return
+	}
+
+	day := 1
+	if value := query.Get("day"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 1 || d > 8 {
+			http.Error(w, "day must be 1 to 8", http.StatusBadRequest)
+			return
+		}
+		day = d
+	}
+
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil {
+		http.Error(w, "lat is not a number", http.StatusBadRequest)
+		return
+	}
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil {
+		http.Error(w, "lon is not a number", http.StatusBadRequest)
+		return
+	}
+
+	at := time.Now().UTC()
+	if value := query.Get("time"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "time is not a valid RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+
+	outlook, categories, err := db.QueryOutlooks(outlookType, day, at, [2]float64{lon, lat})
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to find the outlook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Outlook    *parsers.PTS       `json:"outlook"`
+		Categories []parsers.PTSMatch `json:"categories"`
+	}{outlook, categories})
+}
+
+// The counties in a watch for ?id=TOA00252024&time=RFC3339
+func watchCounties(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	id := query.Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	at := time.Now().UTC()
+	if value := query.Get("time"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "time is not a valid RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+
+	counties, err := db.QueryWatchCounties(id, at)
+	if errors.Is(err, db.ErrWatchNotFound) {
+		http.Error(w, "watch "+id+" not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to find the watch counties", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counties)
+}
+
+// The VTEC events of a hazard for ?hazard=TO.W&start=YYYY-MM-DD&end=YYYY-MM-DD, including its old and new codes
+func hazards(w http.ResponseWriter, r *http.Request) {
+	dateLayout := "2006-01-02"
+	query := r.URL.Query()
+
+	hazard := strings.Split(strings.ToUpper(query.Get("hazard")), ".")
+	if len(hazard) != 2 || len(hazard[0]) != 2 || len(hazard[1]) != 1 {
+		http.Error(w, "hazard must be phenomena.significance, e.g. TO.W", http.StatusBadRequest)
+		return
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if value := query.Get("end"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "end is not a valid date", http.StatusBadRequest)
+			return
+		}
+		end = t
+	}
+
+	start := end.Add(-30 * 24 * time.Hour)
+	if value := query.Get("start"); value != "" {
+		t, err := time.Parse(dateLayout, value)
+		if err != nil {
+			http.Error(w, "start is not a valid date", http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+
+	if !start.Before(end) {
+		http.Error(w, "start must be before end", http.StatusBadRequest)
+		return
+	}
+
+	results, err := db.QueryHazardProducts(hazard[0], hazard[1], start, end)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to find the hazard", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// The CAP 1.2 alert for a VTEC segment of a text product for ?product=ID&segment=N, the first segment by default
+func capAlert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	id := query.Get("product")
+	if id == "" {
+		http.Error(w, "product is required", http.StatusBadRequest)
+		return
+	}
+
+	segment := 0
+	if value := query.Get("segment"); value != "" {
+		s, err := strconv.Atoi(value)
+		if err != nil || s < 0 {
+			http.Error(w, "segment is not valid", http.StatusBadRequest)
+			return
+		}
+		segment = s
+	}
+
+	alerts, err := db.QueryCAPAlerts(id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to build the CAP alerts", http.StatusInternalServerError)
+		return
+	}
+	if segment >= len(alerts) {
+		http.Error(w, "the product does not have that many segments", http.StatusNotFound)
+		return
+	}
+
+	out, err := alerts[segment].XML()
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "failed to build the CAP alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/cap+xml")
+	w.Write(out)
+}
+
 func main() {
+	if err := db.SurrealInit(); err != nil {
+		log.Fatalf("Failed to connect to DB: %s", err.Error())
+	}
+
 	http.HandleFunc("/", first)
+	http.HandleFunc("/verification", verification)
+	http.HandleFunc("/products", products)
+	http.HandleFunc("/outlooks", outlooks)
+	http.HandleFunc("/watches/counties", watchCounties)
+	http.HandleFunc("/cap", capAlert)
+	http.HandleFunc("/hazards", hazards)
 
 	http.ListenAndServe(":3333", nil)
 }
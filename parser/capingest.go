@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/db"
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+)
+
+const (
+	DefaultCAPURL = "https://api.weather.gov/alerts/active.atom"
+	// How often to check the CAP source for new alerts
+	CAPPollInterval = time.Duration(1 * time.Minute)
+	// How long to wait for the NWWS-OI copy of a product before using the CAP version
+	CAPGracePeriod = time.Duration(5 * time.Minute)
+)
+
+type CAPIngestSettings struct {
+	URL string
+	Dir string
+}
+
+func fetchCAP(url string) ([]byte, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The NWS API asks for a user agent that identifies the application
+	request.Header.Set("User-Agent", "NWWS-GO")
+	request.Header.Set("Accept", "application/atom+xml, application/cap+xml, application/xml")
+
+	res, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CAP request to %s returned %s", url, res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+// Get every alert document from the source. An ATOM feed is followed to the alerts it lists
+func readCAPDocuments(settings CAPIngestSettings) ([][]byte, error) {
+	documents := [][]byte{}
+
+	if settings.Dir != "" {
+		entries, err := os.ReadDir(settings.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.ToLower(filepath.Ext(entry.Name())) != ".xml" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(settings.Dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, data)
+		}
+	} else {
+		data, err := fetchCAP(settings.URL)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, data)
+	}
+
+	alerts := [][]byte{}
+	for _, data := range documents {
+		kind, err := parsers.CAPDocumentType(data)
+		if err != nil {
+			log.Printf("Skipping CAP document: %s\n", err.Error())
+			continue
+		}
+		if kind != "feed" {
+			alerts = append(alerts, data)
+			continue
+		}
+
+		entries, err := parsers.ParseCAPAtom(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			// The feed lists every active alert so only fetch the ones that haven't been stored yet
+			if stored, err := db.CAPAlertStored(entry.Identifier, entry.Sent); err == nil && stored {
+				continue
+			}
+			alert, err := fetchCAP(entry.Link)
+			if err != nil {
+				log.Printf("Failed to fetch CAP alert %s: %s\n", entry.Link, err.Error())
+				continue
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts, nil
+}
+
+/*
+RunCAPIngest polls a CAP source as a second feed alongside NWWS-OI. The source is --url, an ATOM feed or
+a single alert, or --dir, a directory of alert XML files. CAP_URL is used when neither is given.
+*/
+func RunCAPIngest(args []string) error {
+	settings := CAPIngestSettings{
+		URL: os.Getenv("CAP_URL"),
+	}
+	if settings.URL == "" {
+		settings.URL = DefaultCAPURL
+	}
+
+	argsLen := len(args)
+	for index := 0; index < argsLen; index++ {
+		arg := args[index]
+		if index+1 >= argsLen {
+			return fmt.Errorf("argument %s is missing a value", arg)
+		}
+		index++
+		switch arg {
+		case "--url":
+			settings.URL = args[index]
+		case "--dir":
+			settings.Dir = args[index]
+		default:
+			return fmt.Errorf("unknown argument %s", arg)
+		}
+	}
+
+	for {
+		alerts, err := readCAPDocuments(settings)
+		if err != nil {
+			log.Printf("Error reading CAP alerts: %s\n", err.Error())
+		}
+
+		now := time.Now().UTC()
+		for _, alert := range alerts {
+			if err := db.PushCAPAlert(alert, now, CAPGracePeriod); err != nil {
+				log.Printf("Error on CAP alert: %s\n", err.Error())
+			}
+		}
+
+		if err := db.CrossReferenceCAPAlerts(now, CAPGracePeriod); err != nil {
+			log.Printf("Error cross referencing CAP alerts: %s\n", err.Error())
+		}
+
+		time.Sleep(CAPPollInterval)
+	}
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+	"github.com/surrealdb/surrealdb.go/pkg/marshal"
+)
+
+// Where a CAP alert stands against the NWWS-OI products
+const (
+	CAPPending  = "pending"  // Waiting for the NWWS-OI product
+	CAPMatched  = "matched"  // Every segment was found from NWWS-OI
+	CAPFallback = "fallback" // NWWS-OI never delivered so the CAP version was stored instead
+	CAPNoVTEC   = "no_vtec"  // Nothing to cross reference
+)
+
+type CAPAlertRecord struct {
+	ID            string    `json:"id,omitempty"`
+	Identifier    string    `json:"identifier"`
+	Sender        string    `json:"sender"`
+	Sent          time.Time `json:"sent"`
+	ReceivedAt    time.Time `json:"received_at"`
+	Status        string    `json:"status"`
+	VTEC          []string  `json:"vtec"`
+	Segments      []string  `json:"segments"` // The matching NWWS-OI vtec_segment records
+	Discrepancies []string  `json:"discrepancies,omitempty"`
+	XML           string    `json:"xml"`
+}
+
+// CAP identifiers are URNs full of dots and colons so the record ID is a hash of it
+func capAlertID(identifier string) string {
+	sum := sha1.Sum([]byte(identifier))
+	return hex.EncodeToString(sum[:])
+}
+
+func saveCAPAlert(record *CAPAlertRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	content := map[string]interface{}{}
+	if err = json.Unmarshal(data, &content); err != nil {
+		return err
+	}
+	delete(content, "id")
+
+	_, err = Surreal().Update("cap_alerts:"+capAlertID(record.Identifier), content)
+	return err
+}
+
+// CAPAlertStored returns true if the alert has already been stored. sent is only checked when it is given
+func CAPAlertStored(identifier string, sent time.Time) (bool, error) {
+	existing, err := marshal.SmartUnmarshal[CAPAlertRecord](Surreal().Select("cap_alerts:" + capAlertID(identifier)))
+	if err != nil || len(existing) == 0 {
+		return false, err
+	}
+	return sent.IsZero() || existing[0].Sent.Equal(sent), nil
+}
+
+// PushCAPAlert stores a CAP alert the first time it is seen and cross references it with NWWS-OI
+func PushCAPAlert(data []byte, now time.Time, grace time.Duration) error {
+	alert, err := parsers.ParseCAPAlert(data)
+	if err != nil {
+		return err
+	}
+
+	sent, err := time.Parse(time.RFC3339, alert.Sent)
+	if err != nil {
+		return fmt.Errorf("could not parse CAP sent time %s", alert.Sent)
+	}
+
+	if stored, err := CAPAlertStored(alert.Identifier, sent.UTC()); err == nil && stored {
+		return nil
+	}
+
+	record := CAPAlertRecord{
+		Identifier: alert.Identifier,
+		Sender:     alert.Sender,
+		Sent:       sent.UTC(),
+		ReceivedAt: now,
+		Status:     CAPPending,
+		VTEC:       []string{},
+		Segments:   []string{},
+		XML:        string(data),
+	}
+	for _, info := range alert.Info {
+		for _, v := range info.Parameter {
+			if v.ValueName == "VTEC" {
+				record.VTEC = append(record.VTEC, v.Value)
+			}
+		}
+	}
+
+	return crossReferenceCAPAlert(&record, now, grace)
+}
+
+/*
+Match each segment of the alert to the same segment from NWWS-OI and note any differences. Once the grace
+period has passed, segments that never arrived from NWWS-OI are stored from CAP so alerting carries on.
+*/
+func crossReferenceCAPAlert(record *CAPAlertRecord, now time.Time, grace time.Duration) error {
+	alert, err := parsers.ParseCAPAlert([]byte(record.XML))
+	if err != nil {
+		return err
+	}
+	product, err := alert.VTECProduct()
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		record.Status = CAPNoVTEC
+		return saveCAPAlert(record)
+	}
+
+	matched := []string{}
+	discrepancies := []string{}
+	missing := []parsers.VTECSegment{}
+	for _, segment := range product.Segments {
+		found, err := findVTECSegment(segment, parsers.SourceCAP)
+		if err != nil {
+			return err
+		}
+		if found == nil {
+			missing = append(missing, segment)
+			continue
+		}
+		matched = append(matched, found.ID)
+
+		nwws := parsers.VTECSegment{
+			VTEC:    found.VTEC,
+			UGC:     found.UGC,
+			Polygon: found.Polygon,
+			Expires: found.Expires,
+		}
+		for _, difference := range parsers.CompareSegments(segment, nwws) {
+			discrepancies = append(discrepancies, segment.VTEC.Original+": "+difference)
+		}
+	}
+
+	if len(missing) > 0 && now.Sub(record.Sent) < grace {
+		record.Status = CAPPending
+		return saveCAPAlert(record)
+	}
+
+	for _, id := range matched {
+		_, err = Surreal().Query(fmt.Sprintf("RELATE cap_alerts:%s->cap_nwws->%s", capAlertID(record.Identifier), id), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
+	record.Segments = matched
+	record.Discrepancies = discrepancies
+	record.Status = CAPMatched
+	if len(missing) > 0 {
+		log.Printf("CAP alert %s: %d segments never arrived from NWWS-OI, storing from CAP\n", record.Identifier, len(missing))
+		product.Segments = missing
+		if err = PushTextProduct(*product.Product); err != nil {
+			return err
+		}
+		if err = PushVTECProduct(product); err != nil {
+			return err
+		}
+		record.Status = CAPFallback
+	}
+	if len(discrepancies) > 0 {
+		log.Printf("CAP alert %s differs from NWWS-OI: %v\n", record.Identifier, discrepancies)
+	}
+
+	return saveCAPAlert(record)
+}
+
+// CrossReferenceCAPAlerts tries again with the alerts still waiting for NWWS-OI
+func CrossReferenceCAPAlerts(now time.Time, grace time.Duration) error {
+	records, err := marshal.SmartUnmarshal[CAPAlertRecord](Surreal().Query("SELECT * FROM cap_alerts WHERE status = $status", map[string]interface{}{
+		"status": CAPPending,
+	}))
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+		if err = crossReferenceCAPAlert(&records[i], now, grace); err != nil {
+			log.Printf("Error cross referencing CAP alert %s: %s\n", records[i].Identifier, err.Error())
+		}
+	}
+
+	return nil
+}
+
+/*
+QueryCAPAlerts builds the CAP alerts for a stored text product, one for each VTEC segment. Updates and
+cancellations refer back to the alerts for the earlier segments of their event.
+*/
+func QueryCAPAlerts(id string) ([]*parsers.CAPAlert, error) {
+	records, err := marshal.SmartUnmarshal[TextProduct](Surreal().Query("SELECT * FROM type::thing('text_products', $id)", map[string]interface{}{
+		"id": id,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("text product " + id + " not found")
+	}
+
+	product, err := parsers.NewAWIPSProduct(records[0].Text)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || !product.HasVTEC() {
+		return nil, errors.New("text product " + id + " has no VTEC")
+	}
+	product.ID = id
+
+	vtecProduct, err := product.VTECProduct()
+	if err != nil {
+		return nil, err
+	}
+
+	return vtecProduct.CAPAlerts(func(segment parsers.VTECSegment) ([]parsers.CAPReference, error) {
+		year, err := vtecEventYear(segment.VTEC, segment.Issued)
+		if err != nil {
+			return nil, err
+		}
+		events, err := marshal.SmartUnmarshal[struct {
+			Segments []VTECSegment `json:"segments"`
+		}](Surreal().Query("SELECT ->vtec_product_segments->vtec_segment.* AS segments FROM type::thing('vtec_product', $id)", map[string]interface{}{
+			"id": vtecEventID(segment.VTEC, year),
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		references := []parsers.CAPReference{}
+		for _, event := range events {
+			for _, earlier := range event.Segments {
+				if !earlier.Issued.Before(segment.Issued) {
+					continue
+				}
+				references = append(references, parsers.CAPReferenceTo(parsers.VTECSegment{
+					VTEC:   earlier.VTEC,
+					UGC:    earlier.UGC,
+					Issued: earlier.Issued,
+				}))
+			}
+		}
+		sort.Slice(references, func(i, j int) bool {
+			return references[i].Sent.Before(references[j].Sent)
+		})
+		return references, nil
+	})
+}
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -57,70 +57,181 @@ func SurrealInit() error {
 		}
 
 		surreal = db
+
+		if err = DefineTextProductSearch(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func PushTextProduct(product parsers.Product) error {
-	product.WFO = "wfo:" + product.WFO
+type VTECProduct struct {
+	ID           string                       `json:"id"`
+	Created_At   time.Time                    `json:"created_at,omitempty"`
+	UpdatedAt    time.Time                    `json:"updated_at,omitempty"`
+	Start        time.Time                    `json:"start"`
+	End          time.Time                    `json:"end"`
+	Issued       time.Time                    `json:"issued"`
+	Expires      time.Time                    `json:"expires"`
+	EndInitial   time.Time                    `json:"end_initial"`
+	EventNumber  int                          `json:"event_number"`
+	Action       string                       `json:"action"`
+	Phenomena    string                       `json:"phenomena"`
+	Significance string                       `json:"significance"`
+	Polygon      *parsers.PolygonFeature      `json:"polygon,omitempty"`
+	Geometry     *parsers.MultiPolygonFeature `json:"geometry,omitempty"`
+	Title        string                       `json:"title,omitempty"`
+	Hazard       string                       `json:"hazard,omitempty"` // The pair as issued, e.g. TO.W
+	WFO          string                       `json:"wfo"`
+	Children     int                          `json:"children,omitempty"`
+}
 
-	// Push the text product to the database
-	_, err := Surreal().Create("text_products", product)
-	return err
+type VTECSegment struct {
+	ID           string                       `json:"id,omitempty"`
+	Created_At   time.Time                    `json:"created_at,omitempty"`
+	Original     string                       `json:"original"`
+	Start        time.Time                    `json:"start"`   // From VTEC
+	End          time.Time                    `json:"end"`     // From VTEC
+	Issued       time.Time                    `json:"issued"`  // From WMO line
+	Expires      time.Time                    `json:"expires"` // From UGC
+	EventNumber  int                          `json:"event_number"`
+	Action       string                       `json:"action"`
+	Phenomena    string                       `json:"phenomena"`
+	Significance string                       `json:"significance"`
+	Polygon      *parsers.PolygonFeature      `json:"polygon,omitempty"`
+	VTEC         parsers.PVTEC                `json:"vtec"`
+	HVTEC        *parsers.HVTEC               `json:"hvtec,omitempty"`
+	UGC          parsers.UGC                  `json:"ugc"`
+	Zones        *parsers.UGCResolution       `json:"zones,omitempty"`
+	Geometry     *parsers.MultiPolygonFeature `json:"geometry,omitempty"`
+	LatLon       *parsers.LATLON              `json:"latlon,omitempty"`
+	TML          *parsers.TML                 `json:"tml,omitempty"`
+	HazardTags   parsers.HazardTags           `json:"tags"`
+	Emergency    bool                         `json:"emergency"`
+	PDS          bool                         `json:"pds"`
+	WFO          string                       `json:"wfo"`
+	Source       string                       `json:"source,omitempty"`
 }
 
-type VTECProduct struct {
-	ID           string                  `json:"id"`
-	Created_At   time.Time               `json:"created_at,omitempty"`
-	UpdatedAt    time.Time               `json:"updated_at,omitempty"`
-	Start        time.Time               `json:"start"`
-	End          time.Time               `json:"end"`
-	Issued       time.Time               `json:"issued"`
-	Expires      time.Time               `json:"expires"`
-	EndInitial   time.Time               `json:"end_initial"`
-	EventNumber  int                     `json:"event_number"`
-	Action       string                  `json:"action"`
-	Phenomena    string                  `json:"phenomena"`
-	Significance string                  `json:"significance"`
-	Polygon      *parsers.PolygonFeature `json:"polygon,omitempty"`
-	Title        string                  `json:"title,omitempty"`
-	WFO          string                  `json:"wfo"`
-	Children     int                     `json:"children,omitempty"`
+type vtecUGCRelation struct {
+	ID           string    `json:"id"`
+	Out          string    `json:"out"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Issued       time.Time `json:"issued"`
+	Expires      time.Time `json:"expires"`
+	Action       string    `json:"action"`
+	Status       string    `json:"status,omitempty"`
+	UpgradedTo   string    `json:"upgraded_to,omitempty"`
+	UpgradedFrom string    `json:"upgraded_from,omitempty"`
 }
 
-type VTECSegment struct {
-	ID           string                  `json:"id,omitempty"`
-	Created_At   time.Time               `json:"created_at,omitempty"`
-	Original     string                  `json:"original"`
-	Start        time.Time               `json:"start"`   // From VTEC
-	End          time.Time               `json:"end"`     // From VTEC
-	Issued       time.Time               `json:"issued"`  // From WMO line
-	Expires      time.Time               `json:"expires"` // From UGC
-	EventNumber  int                     `json:"event_number"`
-	Action       string                  `json:"action"`
-	Phenomena    string                  `json:"phenomena"`
-	Significance string                  `json:"significance"`
-	Polygon      *parsers.PolygonFeature `json:"polygon,omitempty"`
-	VTEC         parsers.PVTEC           `json:"vtec"`
-	HVETC        *parsers.HVTEC          `json:"hvtec,omitempty"` // TODO: Add HVTEC support
-	UGC          parsers.UGC             `json:"ugc"`
-	LatLon       *parsers.LATLON         `json:"latlon,omitempty"`
-	TML          *parsers.TML            `json:"tml,omitempty"`
-	HazardTags   parsers.HazardTags      `json:"tags"`
-	Emergency    bool                    `json:"emergency"`
-	PDS          bool                    `json:"pds"`
-	WFO          string                  `json:"wfo"`
+/*
+Rebuild a VTEC event from the zones already related to the vtec_product record.
+Also returns the vtec_ugc relation ID for each zone so they can be updated in place.
+*/
+func loadVTECEvent(id string, vtec parsers.PVTEC) (*parsers.VTECEvent, map[string]string, error) {
+	event := parsers.NewVTECEvent(vtec)
+	relations := map[string]string{}
+
+	records, err := marshal.SmartUnmarshal[vtecUGCRelation](Surreal().Query(fmt.Sprintf("SELECT * FROM vtec_ugc WHERE in == %s", id), map[string]string{}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range records {
+		code := strings.TrimPrefix(r.Out, "ugc:")
+		action := strings.TrimPrefix(r.Action, "vtec_actions:")
+
+		// Relations stored before zone statuses were tracked only have the action
+		status := r.Status
+		if status == "" {
+			switch action {
+			case "CAN":
+				status = parsers.VTECZoneCancelled
+			case "EXP":
+				status = parsers.VTECZoneExpired
+			case "UPG":
+				status = parsers.VTECZoneUpgraded
+			default:
+				status = parsers.VTECZoneActive
+			}
+		}
+
+		event.Zones[code] = &parsers.VTECEventZone{
+			UGC:          code,
+			Status:       status,
+			Action:       action,
+			Start:        r.Start,
+			End:          r.End,
+			Issued:       r.Issued,
+			Expires:      r.Expires,
+			UpgradedTo:   r.UpgradedTo,
+			UpgradedFrom: r.UpgradedFrom,
+		}
+		relations[code] = r.ID
+	}
+
+	return event, relations, nil
+}
+
+/*
+The ID of the vtec_product record for the event. National ETNs leave out the office so every office
+issuing products for the same watch or tropical system adds to a single event.
+*/
+func vtecEventID(vtec parsers.PVTEC, year int) string {
+	id := vtec.Phenomena + vtec.Significance + util.PadZero(strconv.Itoa(vtec.ETN), 4) + strconv.Itoa(year)
+	if vtec.NationalETN() {
+		return id
+	}
+	return vtec.WFO + id
+}
+
+// Resolve the year the VTEC event was numbered in from the events already stored
+func vtecEventYear(vtec parsers.PVTEC, issued time.Time) (int, error) {
+	return vtec.EventYear(issued, func(year int) (*time.Time, error) {
+		records, err := marshal.SmartUnmarshal[struct {
+			End time.Time `json:"end"`
+		}](Surreal().Query("SELECT end FROM type::thing('vtec_product', $id)", map[string]interface{}{
+			"id": vtecEventID(vtec, year),
+		}))
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		return &records[0].End, nil
+	})
 }
 
 func PushVTECProduct(p *parsers.VTECProduct) error {
 	product := p.Product
 	for _, segment := range p.Segments {
+		// Keep the segment as it was parsed for the event engine
+		parsed := segment
+
+		// The same segment can arrive from more than one source so only the first is kept
+		duplicate, err := findVTECSegment(segment, "")
+		if err != nil {
+			return err
+		}
+		if duplicate != nil {
+			_, err = Surreal().Query(fmt.Sprintf("RELATE text_products:%s->vtec_text_products->%s", product.ID, duplicate.ID), map[string]string{})
+			if err != nil {
+				return err
+			}
+			continue
+		}
 
 		// Create ID
-		year := strconv.Itoa(product.Issued.Year())
+		year, err := vtecEventYear(segment.VTEC, product.Issued)
+		if err != nil {
+			return err
+		}
 
-		vtecID := segment.WFO + segment.Phenomena + segment.Significance + util.PadZero(strconv.Itoa(segment.EventNumber), 4) + year
+		vtecID := vtecEventID(segment.VTEC, year)
 
 		// Get the most recent record if one exists
 		query := fmt.Sprintf(`SELECT *, count(->vtec_product_segments) AS children FROM vtec_product:%s`, vtecID)
@@ -163,6 +274,9 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 				Phenomena:    "phenomena:" + segment.VTEC.Phenomena,
 				Significance: "vtec_significance:" + segment.VTEC.Significance,
 				Polygon:      segment.Polygon,
+				Geometry:     segment.Geometry,
+				Title:        parsers.GetHazardCatalog().Name(segment.VTEC.Phenomena, segment.VTEC.Significance),
+				Hazard:       segment.VTEC.Phenomena + "." + segment.VTEC.Significance,
 				WFO:          "wfo:" + segment.VTEC.WFO,
 				Children:     0,
 			}
@@ -185,18 +299,21 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 			Significance: "vtec_significance:" + segment.VTEC.Significance,
 			Polygon:      segment.Polygon,
 			VTEC:         segment.VTEC,
-			HVETC:        segment.HVETC,
+			HVTEC:        segment.HVTEC,
 			UGC:          segment.UGC,
+			Zones:        segment.Zones,
+			Geometry:     segment.Geometry,
 			LatLon:       segment.LatLon,
 			TML:          segment.TML,
 			HazardTags:   segment.HazardTags,
 			Emergency:    segment.Emergency,
 			PDS:          segment.PDS,
 			WFO:          "wfo:" + segment.VTEC.WFO,
+			Source:       p.Source,
 		}
 
-		// Verify products a little bit
-		if parent.WFO != final.WFO {
+		// Verify products a little bit. National events are shared between offices
+		if parent.WFO != final.WFO && !segment.VTEC.NationalETN() {
 			return fmt.Errorf("vtec WFO mismatch. Found %s needed %s on VTEC %s", final.WFO, parent.WFO, segment.VTEC.Original)
 		}
 		if parent.EventNumber != final.EventNumber {
@@ -209,6 +326,18 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 			return errors.New("vtec significance mismatch")
 		}
 
+		// Play the segment through the event before storing anything so illegal transitions are rejected
+		event, relations, err := loadVTECEvent(parent.ID, segment.VTEC)
+		if err != nil {
+			return err
+		}
+		zones, err := event.Apply(parsed)
+		if err != nil {
+			// One bad segment shouldn't lose the rest of the product
+			log.Printf("Skipping segment %s of %s: %s\n", segment.VTEC.Original, product.ID, err.Error())
+			continue
+		}
+
 		/*
 			Push the VTEC segments first to make sure that will actually work
 		*/
@@ -229,6 +358,14 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 			return err
 		}
 
+		// RELATE the vtec product to the forecast point so river floods can be tracked point by point
+		if segment.HVTEC != nil && segment.HVTEC.PointSpecific() {
+			err = pushHVTEC(parent.ID, id, segment)
+			if err != nil {
+				return err
+			}
+		}
+
 		parent.UpdatedAt = time.Now()
 		if parent.Start.Compare(final.Start) > 0 {
 			parent.Start = final.Start
@@ -247,100 +384,46 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 		}
 
 		// Update UGC
-		for _, s := range final.UGC.States {
-
-			for _, c := range s.Zones {
-				id := "ugc:" + s.Name + s.Type + c
-				// Check to see if the UGC record already exists
-				cz, err := marshal.SmartUnmarshal[struct {
-					CZ []struct {
-						ID      string    `json:"id"`
-						In      string    `json:"in"`
-						Out     string    `json:"out"`
-						Start   time.Time `json:"start"`
-						End     time.Time `json:"end"`
-						Issued  time.Time `json:"issued"`
-						Expires time.Time `json:"expires"`
-						Action  string    `json:"action"`
-					} `json:"cz"`
-				}](Surreal().Query(fmt.Sprintf(`SELECT (SELECT * FROM $parent->vtec_ugc WHERE out == %s) AS cz FROM %s`, id, parent.ID), map[string]string{}))
-				if err != nil {
-					return err
-				}
+		for _, zone := range zones {
+			start, err := (zone.Start.MarshalText())
+			if err != nil {
+				return err
+			}
+			end, err := (zone.End.MarshalText())
+			if err != nil {
+				return err
+			}
+			issued, err := (zone.Issued.MarshalText())
+			if err != nil {
+				return err
+			}
+			expires, err := (zone.Expires.MarshalText())
+			if err != nil {
+				return err
+			}
 
-				if len(cz) != 0 && len(cz[0].CZ) != 0 {
-
-					update := false
-					current := cz[0].CZ[0]
-					if current.Start.Compare(final.Start) > 0 {
-						current.Start = final.Start
-						update = true
-					}
-					if current.End.Compare(final.End) < 0 {
-						current.End = final.End
-						update = true
-					}
-					if current.Expires.Compare(parent.Expires) < 0 {
-						current.Expires = parent.Expires
-						update = true
-					}
-					if current.Action != final.Action {
-						current.Action = final.Action
-						update = true
-					}
-					if update {
-						end, err := (current.End.MarshalText())
-						if err != nil {
-							return err
-						}
-						expires, err := (current.Expires.MarshalText())
-						if err != nil {
-							return err
-						}
-
-						_, err = Surreal().Query("UPDATE $id SET end = $end, expires = $expires, action = $action", map[string]interface{}{
-							"id":      current.ID,
-							"end":     string(end),
-							"expires": string(expires),
-							"action":  current.Action,
-						})
-						if err != nil {
-							return err
-						}
-					}
-				} else {
-					start, err := (final.Start.MarshalText())
-					if err != nil {
-						return err
-					}
-					end, err := (final.End.MarshalText())
-					if err != nil {
-						return err
-					}
-					issued, err := (parent.Issued.MarshalText())
-					if err != nil {
-						return err
-					}
-					expires, err := (parent.Expires.MarshalText())
-					if err != nil {
-						return err
-					}
-
-					// RELATE the county/zones to the product
-					_, err = Surreal().Query("RELATE $product->vtec_ugc->$ugc SET start = $start, end = $end, issued = $issued, expires = $expires, action = $action", map[string]string{
-						"product": parent.ID,
-						"ugc":     id,
-						"start":   string(start),
-						"end":     string(end),
-						"issued":  string(issued),
-						"expires": string(expires),
-						"action":  final.Action,
-					})
-					if err != nil {
-						return err
-					}
+			params := map[string]string{
+				"start":         string(start),
+				"end":           string(end),
+				"issued":        string(issued),
+				"expires":       string(expires),
+				"action":        "vtec_actions:" + zone.Action,
+				"status":        zone.Status,
+				"upgraded_to":   zone.UpgradedTo,
+				"upgraded_from": zone.UpgradedFrom,
+			}
 
-				}
+			if relation, ok := relations[zone.UGC]; ok {
+				params["id"] = relation
+				_, err = Surreal().Query("UPDATE $id SET start = $start, end = $end, issued = $issued, expires = $expires, action = $action, status = $status, upgraded_to = $upgraded_to, upgraded_from = $upgraded_from", params)
+			} else {
+				// RELATE the county/zones to the product
+				params["product"] = parent.ID
+				params["ugc"] = "ugc:" + zone.UGC
+				_, err = Surreal().Query("RELATE $product->vtec_ugc->$ugc SET start = $start, end = $end, issued = $issued, expires = $expires, action = $action, status = $status, upgraded_to = $upgraded_to, upgraded_from = $upgraded_from", params)
+			}
+			if err != nil {
+				return err
 			}
 		}
 
@@ -350,6 +433,13 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 			if err != nil {
 				return err
 			}
+
+			if segment.VTEC.Phenomena == "FF" && segment.VTEC.Significance == "W" && segment.Polygon != nil {
+				err = pushFFWMPDs(parent.ID, product.Issued, segment.Polygon)
+				if err != nil {
+					return err
+				}
+			}
 		} else {
 			updatedAt, err := (parent.UpdatedAt.MarshalText())
 			if err != nil {
@@ -397,54 +487,401 @@ func PushVTECProduct(p *parsers.VTECProduct) error {
 		}
 	}
 
-	PushTextProduct(*product)
-
 	return nil
 }
 
-func PushWatch(watch *parsers.Watch) error {
-	_, err := Surreal().Create("severe_watches", *watch)
+/*
+Find a stored copy of the segment, the same action on the same event issued around the same time for some of
+the same zones. When exclude is given segments from that source are skipped, so a CAP alert isn't matched
+against itself.
+*/
+func findVTECSegment(segment parsers.VTECSegment, exclude string) (*VTECSegment, error) {
+	query := `SELECT * FROM vtec_segment WHERE vtec.wfo = $wfo AND vtec.phenomena = $phenomena AND vtec.significance = $significance
+		AND vtec.etn = $etn AND vtec.action = $action AND issued >= $from AND issued <= $to`
+	if exclude != "" {
+		query += " AND source != $exclude"
+	}
+	segments, err := marshal.SmartUnmarshal[VTECSegment](Surreal().Query(query, map[string]interface{}{
+		"wfo":          segment.VTEC.WFO,
+		"phenomena":    segment.VTEC.Phenomena,
+		"significance": segment.VTEC.Significance,
+		"etn":          segment.VTEC.ETN,
+		"action":       segment.VTEC.Action,
+		"from":         segment.Issued.Add(-parsers.SegmentMatchWindow),
+		"to":           segment.Issued.Add(parsers.SegmentMatchWindow),
+		"exclude":      exclude,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	for i := range segments {
+		stored := parsers.VTECSegment{
+			VTEC:   segments[i].VTEC,
+			UGC:    segments[i].UGC,
+			Issued: segments[i].Issued,
+		}
+		if parsers.SameSegment(segment, stored, parsers.SegmentMatchWindow) {
+			return &segments[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func pushHVTEC(parentID string, segmentID string, segment parsers.VTECSegment) error {
+	params := map[string]interface{}{
+		"product":       parentID,
+		"nwsli":         "nwsli:" + segment.HVTEC.NWSLI,
+		"segment":       "vtec_segment:" + segmentID,
+		"issued":        segment.Issued,
+		"action":        "vtec_actions:" + segment.VTEC.Action,
+		"severity":      segment.HVTEC.Severity,
+		"severity_name": segment.HVTEC.SeverityName(),
+		"cause":         segment.HVTEC.Cause,
+		"cause_name":    segment.HVTEC.CauseName(),
+		"record":        segment.HVTEC.Record,
+		"record_name":   segment.HVTEC.RecordName(),
+		"begin":         segment.HVTEC.Begin,
+		"crest":         segment.HVTEC.Crest,
+		"end":           segment.HVTEC.End,
+	}
 
+	_, err := Surreal().Query("RELATE $product->vtec_hvtec->$nwsli SET segment = $segment, issued = $issued, action = $action, severity = $severity, severity_name = $severity_name, cause = $cause, cause_name = $cause_name, record = $record, record_name = $record_name, begin = $begin, crest = $crest, end = $end", params)
 	return err
 }
 
-func PushMCD(mcd *parsers.MCD, p *parsers.Product) error {
-	concerningRegexp := regexp.MustCompile(`(Concerning\.\.\.)([A-Za-z0-9 \.\n]+)\n\n`)
-	concerningLine := strings.TrimSpace(concerningRegexp.FindString(mcd.Original))
-	concerningLine = strings.Replace(concerningLine, "Concerning...", "", 1)
-	if concerningLine != "" {
-		phenomenaRegexp := regexp.MustCompile("(Severe Thunderstorm Watch|Tornado Watch) ([0-9]+)")
-		phenomenaString := phenomenaRegexp.FindString(concerningLine)
-		if phenomenaString != "" {
-			phenomena := "TO"
-			if phenomenaString == "" {
-				phenomena = "SV"
-			}
+// Load a stored watch by its ID, without the table. Returns nil if it hasn't been seen yet
+func loadWatch(id string) (*parsers.Watch, error) {
+	records, err := marshal.SmartUnmarshal[parsers.Watch](Surreal().Select("severe_watches:" + id))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	watch := records[0]
+	watch.ID = strings.TrimPrefix(watch.ID, "severe_watches:")
+	return &watch, nil
+}
 
-			watchNumberRegexp := regexp.MustCompile(`[0-9]+`)
-			watchNumber := watchNumberRegexp.FindString(concerningLine)
-			if watchNumber == "" {
-				return errors.New("Found concerning watch in MCD but couldn't parse number MCD " + strconv.Itoa(mcd.Number))
-			}
-			watchID := "severe_watches:" + phenomena + "A" + util.PadZero(watchNumber, 4) + strconv.Itoa(mcd.Issued.Year())
+func saveWatch(watch *parsers.Watch) error {
+	// The record ID comes from the thing so leave it out of the content
+	data, err := json.Marshal(watch)
+	if err != nil {
+		return err
+	}
+	content := map[string]interface{}{}
+	if err = json.Unmarshal(data, &content); err != nil {
+		return err
+	}
+	delete(content, "id")
 
-			// RELATE the watch product to the segment
-			_, err := Surreal().Query(fmt.Sprintf("RELATE mcd:%s->mcd_watch->%s", mcd.ID, watchID), map[string]string{})
-			if err != nil {
+	_, err = Surreal().Update("severe_watches:"+watch.ID, content)
+	return err
+}
+
+/*
+PushWatch merges a product of a watch into the stored watch, creating it if this is the first product
+to arrive. Watches it replaces are marked as replaced.
+*/
+func PushWatch(piece *parsers.Watch) error {
+	// A product for a watch issued on Dec 31 can arrive on Jan 1
+	year, err := parsers.WatchYear(piece.FirstSeen, piece.Issued, func(year int) (*time.Time, error) {
+		watch, err := loadWatch(parsers.WatchID(piece.Type, piece.Number, year))
+		if err != nil || watch == nil {
+			return nil, err
+		}
+		if watch.Expires != nil {
+			return watch.Expires, nil
+		}
+		return &watch.UpdatedAt, nil
+	})
+	if err != nil {
+		return err
+	}
+	piece.SetYear(year)
+
+	watch, err := loadWatch(piece.ID)
+	if err != nil {
+		return err
+	}
+
+	if watch == nil {
+		watch = piece
+	} else if err = watch.Merge(piece); err != nil {
+		return err
+	}
+
+	if watch.UpdateStatus(time.Now().UTC()) {
+		log.Printf("Watch %s is %s\n", watch.ID, watch.Status)
+	}
+	if err = saveWatch(watch); err != nil {
+		return err
+	}
+
+	// The SAW brings the watch box so the MCDs can be matched once it arrives
+	if piece.SAW != nil {
+		if err = pushWatchMCDs(watch); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range piece.Replaces {
+		replaced, err := loadWatch(id)
+		if err != nil {
+			return err
+		}
+		if replaced == nil {
+			continue
+		}
+		replaced.Status = parsers.WatchReplaced
+		replaced.ReplacedBy = watch.ID
+		if err = saveWatch(replaced); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateWatchStatuses times out watches that never got all of their products and expires those that have ended
+func UpdateWatchStatuses(now time.Time) error {
+	watches, err := marshal.SmartUnmarshal[parsers.Watch](Surreal().Query("SELECT * FROM severe_watches WHERE status IN $statuses", map[string]interface{}{
+		"statuses": []string{parsers.WatchPending, parsers.WatchPartial, parsers.WatchActive},
+	}))
+	if err != nil {
+		return err
+	}
+
+	for i := range watches {
+		watch := &watches[i]
+		watch.ID = strings.TrimPrefix(watch.ID, "severe_watches:")
+		if watch.UpdateStatus(now) {
+			log.Printf("Watch %s is %s\n", watch.ID, watch.Status)
+			if err = saveWatch(watch); err != nil {
 				return err
 			}
 		}
 	}
 
-	mcd.Concerning = concerningLine
+	return nil
+}
+
+// Given when there is no stored watch with the ID
+var ErrWatchNotFound = errors.New("watch not found")
 
+// QueryWatchCounties returns the counties that were in a watch at a point in time
+func QueryWatchCounties(id string, at time.Time) ([]string, error) {
+	watch, err := loadWatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if watch == nil {
+		return nil, fmt.Errorf("%w: %s", ErrWatchNotFound, id)
+	}
+	return watch.CountiesAt(at), nil
+}
+
+func PushMCD(mcd *parsers.MCD, p *parsers.Product) error {
 	_, err := Surreal().Create("mcd", mcd)
 	if err != nil {
 		return err
 	}
 
+	// RELATE the MCD to every watch it mentions
+	for _, watch := range mcd.Watches {
+		_, err = Surreal().Query(fmt.Sprintf("RELATE mcd:%s->mcd_watch->severe_watches:%s", mcd.ID, watch.ID), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
 	// RELATE the text product to the mcd
 	_, err = Surreal().Query(fmt.Sprintf("RELATE text_products:%s->mcd_text_products->mcd:%s", p.ID, mcd.ID), map[string]string{})
 
 	return err
 }
+
+func PushMPD(mpd *parsers.MCD, p *parsers.Product) error {
+	_, err := Surreal().Create("mpd", mpd)
+	if err != nil {
+		return err
+	}
+
+	// RELATE the text product to the mpd
+	_, err = Surreal().Query(fmt.Sprintf("RELATE text_products:%s->mpd_text_products->mpd:%s", p.ID, mpd.ID), map[string]string{})
+
+	return err
+}
+
+// Link a new Flash Flood Warning to the MPDs it falls within
+func pushFFWMPDs(parentID string, issued time.Time, polygon *parsers.PolygonFeature) error {
+	mpds, err := marshal.SmartUnmarshal[parsers.MCD](Surreal().Query("SELECT * FROM mpd WHERE issued <= $issued AND expires >= $issued", map[string]interface{}{
+		"issued": issued,
+	}))
+	if err != nil {
+		return err
+	}
+
+	for i := range mpds {
+		mpd := &mpds[i]
+		mpd.ID = strings.TrimPrefix(mpd.ID, "mpd:")
+		if !mpd.Overlaps(issued, polygon) {
+			continue
+		}
+		_, err = Surreal().Query(fmt.Sprintf("RELATE mpd:%s->mpd_ffw->%s", mpd.ID, parentID), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Link a newly issued watch to the MCDs that led to it
+func pushWatchMCDs(watch *parsers.Watch) error {
+	if watch.Issued == nil || watch.Polygon == nil {
+		return nil
+	}
+
+	mcds, err := marshal.SmartUnmarshal[parsers.MCD](Surreal().Query("SELECT * FROM mcd WHERE issued <= $issued AND expires >= $issued", map[string]interface{}{
+		"issued": watch.Issued,
+	}))
+	if err != nil {
+		return err
+	}
+
+	for i := range mcds {
+		mcd := &mcds[i]
+		mcd.ID = strings.TrimPrefix(mcd.ID, "mcd:")
+		if !mcd.LedTo(watch) {
+			continue
+		}
+		_, err = Surreal().Query(fmt.Sprintf("RELATE mcd:%s->mcd_led_to->severe_watches:%s", mcd.ID, watch.ID), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func PushLSR(reports []parsers.LSR, p *parsers.Product) error {
+	for _, report := range reports {
+		_, err := Surreal().Create("lsr", report)
+		if err != nil {
+			return err
+		}
+
+		// RELATE the text product to the report
+		_, err = Surreal().Query(fmt.Sprintf("RELATE text_products:%s->lsr_text_products->lsr:%s", p.ID, report.ID), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+QueryHazardProducts finds the VTEC products for a hazard that started between start and end. Codes that were
+renamed or consolidated under hazard simplification are included, so HZ.W also gives FZ.W and the other way round.
+*/
+func QueryHazardProducts(phenomena string, significance string, start time.Time, end time.Time) ([]VTECProduct, error) {
+	return marshal.SmartUnmarshal[VTECProduct](Surreal().Query("SELECT * FROM vtec_product WHERE hazard IN $hazards AND start >= $start AND start < $end ORDER BY start", map[string]interface{}{
+		"hazards": parsers.GetHazardCatalog().Equivalent(phenomena, significance),
+		"start":   start,
+		"end":     end,
+	}))
+}
+
+/*
+QueryVerification verifies the storm-based warnings that started between start and end against the
+storm reports from start until the last of them expires. Warnings are verified on their polygon and valid time
+as first issued.
+*/
+func QueryVerification(start time.Time, end time.Time, period string) ([]parsers.VerificationResult, error) {
+	type warningRecord struct {
+		VTECProduct
+		InitialPolygon *parsers.PolygonFeature `json:"initial_polygon,omitempty"`
+	}
+
+	records, err := marshal.SmartUnmarshal[warningRecord](Surreal().Query(`SELECT *, (SELECT polygon, issued FROM $parent->vtec_product_segments->vtec_segment WHERE action = "NEW" ORDER BY issued LIMIT 1)[0].polygon AS initial_polygon FROM vtec_product WHERE phenomena IN $phenomena AND significance = $significance AND start >= $start AND start < $end`, map[string]interface{}{
+		"phenomena":    []string{"phenomena:TO", "phenomena:SV", "phenomena:FF"},
+		"significance": "vtec_significance:W",
+		"start":        start,
+		"end":          end,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Reports are wanted up to the end of the last warning, which can run past the end of the period
+	reportsEnd := end
+	warnings := []parsers.VerificationWarning{}
+	for _, record := range records {
+		if record.EndInitial.After(reportsEnd) {
+			reportsEnd = record.EndInitial
+		}
+		polygon := record.InitialPolygon
+		if polygon == nil {
+			polygon = record.Polygon
+		}
+		warnings = append(warnings, parsers.VerificationWarning{
+			ID:        strings.TrimPrefix(record.ID, "vtec_product:"),
+			WFO:       strings.TrimPrefix(record.WFO, "wfo:"),
+			Phenomena: strings.TrimPrefix(record.Phenomena, "phenomena:"),
+			Start:     record.Start,
+			End:       record.EndInitial,
+			Polygon:   polygon,
+		})
+	}
+
+	// A report at the minute a warning expires still verifies it
+	reports, err := marshal.SmartUnmarshal[parsers.LSR](Surreal().Query("SELECT * FROM lsr WHERE time >= $start AND time <= $end", map[string]interface{}{
+		"start": start,
+		"end":   reportsEnd,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsers.Verify(warnings, reports, period), nil
+}
+
+func PushPTS(outlooks []parsers.PTS, p *parsers.Product) error {
+	for _, outlook := range outlooks {
+		_, err := Surreal().Create("spc_outlook", outlook)
+		if err != nil {
+			return err
+		}
+
+		// RELATE the text product to the outlook
+		_, err = Surreal().Query(fmt.Sprintf("RELATE text_products:%s->spc_outlook_text_products->spc_outlook:%s", p.ID, outlook.ID), map[string]string{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+QueryOutlooks finds the latest SPC outlook of the type for the day that is valid at the time,
+along with the categories that cover the point.
+*/
+func QueryOutlooks(outlookType string, day int, at time.Time, point [2]float64) (*parsers.PTS, []parsers.PTSMatch, error) {
+	records, err := marshal.SmartUnmarshal[parsers.PTS](Surreal().Query("SELECT * FROM spc_outlook WHERE type == $type AND day == $day AND start <= $at AND expires > $at ORDER BY issued DESC LIMIT 1", map[string]interface{}{
+		"type": outlookType,
+		"day":  day,
+		"at":   at.UTC(),
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, []parsers.PTSMatch{}, nil
+	}
+
+	outlook := records[0]
+	return &outlook, outlook.CategoriesAt(point), nil
+}
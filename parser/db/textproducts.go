@@ -0,0 +1,153 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+	"github.com/surrealdb/surrealdb.go/pkg/marshal"
+)
+
+// The most products a search gives back
+const TextProductSearchLimit = 500
+
+// A text product as stored, with the header flattened so it can be searched on
+type TextProduct struct {
+	ID           string         `json:"id,omitempty"`
+	Group        string         `json:"group"`
+	Text         string         `json:"text"`
+	WMO          parsers.WMO    `json:"wmo"`
+	AWIPS        *parsers.AWIPS `json:"awips,omitempty"` // Kept as the object the products have always been stored with
+	Product      string         `json:"product"`
+	WFO          string         `json:"wfo"`
+	BIL          string         `json:"bil,omitempty"`
+	Issued       time.Time      `json:"issued"`
+	IssuedSource string         `json:"issued_source,omitempty"`
+	Source       string         `json:"source,omitempty"`
+	Family       string         `json:"family,omitempty"`
+	Sequence     string         `json:"sequence,omitempty"`
+	DataType     string         `json:"data_type,omitempty"`
+	Correction   bool           `json:"correction"`
+	ReceivedAt   time.Time      `json:"received_at"`
+	Score        float64        `json:"score,omitempty"` // How well the text matched a search
+}
+
+/*
+DefineTextProductSearch sets up the indexes the text products are searched with. The text is indexed for
+full-text search with BM25 so the best matches can be ranked first.
+*/
+func DefineTextProductSearch() error {
+	_, err := Surreal().Query(`
+		DEFINE ANALYZER IF NOT EXISTS text_product_analyzer TOKENIZERS blank,class,punct FILTERS lowercase,ascii,snowball(english);
+		DEFINE INDEX IF NOT EXISTS text_products_text ON text_products FIELDS text SEARCH ANALYZER text_product_analyzer BM25 HIGHLIGHTS;
+		DEFINE INDEX IF NOT EXISTS text_products_wfo ON text_products FIELDS wfo, issued;
+		DEFINE INDEX IF NOT EXISTS text_products_awips_original ON text_products FIELDS awips.original, issued;
+		DEFINE INDEX IF NOT EXISTS text_products_group ON text_products FIELDS group;
+	`, map[string]string{})
+	return err
+}
+
+/*
+MigrateTextProductAWIPS puts back the AWIPS object on any product that was stored with just the identifier.
+It only needs to run once against a database written before the object was stored, with --migrate.
+*/
+func MigrateTextProductAWIPS() error {
+	_, err := Surreal().Query(`
+		UPDATE text_products SET awips = {
+			original: awips,
+			product: string::slice(awips, 0, 3),
+			wfo: string::slice(awips, 3)
+		} WHERE type::is::string(awips);
+	`, map[string]string{})
+	return err
+}
+
+// PushTextProduct stores the product as received, whether or not anything else was parsed from it
+func PushTextProduct(product parsers.Product) error {
+	record := TextProduct{
+		ID:           product.ID,
+		Group:        product.Group,
+		Text:         product.Text,
+		WMO:          product.WMO,
+		Product:      product.Product,
+		WFO:          "wfo:" + product.WFO,
+		BIL:          product.BIL,
+		Issued:       product.Issued,
+		IssuedSource: product.IssuedSource,
+		Source:       product.Source,
+		Family:       product.Family,
+		Correction:   strings.HasPrefix(product.WMO.BBB, "CC"),
+		ReceivedAt:   time.Now().UTC(),
+	}
+	if product.AWIPS.Original != "" {
+		awips := product.AWIPS
+		record.AWIPS = &awips
+	}
+	if product.Header != nil {
+		record.Sequence = product.Header.Sequence
+		record.DataType = product.Header.DataType
+	}
+
+	// Push the text product to the database
+	_, err := Surreal().Create("text_products", record)
+	return err
+}
+
+type TextProductQuery struct {
+	WFO   string    // e.g. TOP
+	AWIPS string    // The full identifier, e.g. AFDTOP, or just the product, e.g. AFD
+	Start time.Time // Issued at or after
+	End   time.Time // Issued before
+	Text  string    // Words to search the text for
+	Limit int
+}
+
+// SearchTextProducts finds the stored text products that match every part of the query, newest or best matching first
+func SearchTextProducts(query TextProductQuery) ([]TextProduct, error) {
+	where := []string{}
+	params := map[string]interface{}{}
+
+	if query.WFO != "" {
+		where = append(where, "wfo = $wfo")
+		params["wfo"] = "wfo:" + strings.ToUpper(query.WFO)
+	}
+	switch len(query.AWIPS) {
+	case 0:
+	case 3:
+		where = append(where, "product = $product")
+		params["product"] = strings.ToUpper(query.AWIPS)
+	default:
+		where = append(where, "awips.original = $awips")
+		params["awips"] = strings.ToUpper(query.AWIPS)
+	}
+	if !query.Start.IsZero() {
+		where = append(where, "issued >= $start")
+		params["start"] = query.Start.UTC()
+	}
+	if !query.End.IsZero() {
+		where = append(where, "issued < $end")
+		params["end"] = query.End.UTC()
+	}
+
+	order := "issued DESC"
+	fields := "*"
+	if query.Text != "" {
+		where = append(where, "text @1@ $text")
+		params["text"] = query.Text
+		order = "score DESC, issued DESC"
+		fields = "*, search::score(1) AS score"
+	}
+
+	if len(where) == 0 {
+		return nil, errors.New("text product search needs at least one of WFO, AWIPS, time or text")
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > TextProductSearchLimit {
+		limit = TextProductSearchLimit
+	}
+	params["limit"] = limit
+
+	return marshal.SmartUnmarshal[TextProduct](Surreal().Query("SELECT "+fields+" FROM text_products WHERE "+strings.Join(where, " AND ")+" ORDER BY "+order+" LIMIT $limit", params))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+)
+
+// Load a newer hazard catalog if HAZARD_CATALOG points at one. The catalog shipped with the parser is used if not
+func loadHazardCatalog() {
+	path := os.Getenv("HAZARD_CATALOG")
+	if path == "" {
+		return
+	}
+	catalog, err := parsers.LoadHazardCatalog(path)
+	if err != nil {
+		log.Printf("Failed to load the hazard catalog from %s: %s\n", path, err.Error())
+		return
+	}
+	parsers.SetHazardCatalog(catalog)
+	log.Printf("Loaded hazard catalog version %s with %d hazards\n", catalog.Version, len(catalog.Hazards))
+}
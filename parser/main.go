@@ -11,7 +11,8 @@ import (
 )
 
 const (
-	PurgeTime time.Duration = time.Duration(30 * time.Minute)
+	PurgeTime           time.Duration = time.Duration(30 * time.Minute)
+	WatchStatusInterval time.Duration = time.Duration(1 * time.Minute)
 )
 
 type pendingProduct struct {
@@ -65,11 +66,21 @@ func runLatestParser() error {
 
 	fmt.Println("Listening for products")
 
+	// Keep watch statuses current even when no products arrive for them
+	go func() {
+		ticker := time.NewTicker(WatchStatusInterval)
+		for range ticker.C {
+			if err := db.UpdateWatchStatuses(time.Now().UTC()); err != nil {
+				log.Printf("Error updating watch statuses: %s\n", err)
+			}
+		}
+	}()
+
 	go func() {
 		for notification := range notifications {
-if notification.Action != "CREATE" {
- continue
-}
+			if notification.Action != "CREATE" {
+				continue
+			}
 			// Handle each incoming notification
 			var product pendingProduct
 			err := marshal.Unmarshal(notification.Result, &product)
@@ -134,8 +145,6 @@ if notification.Action != "CREATE" {
 	// 		return err
 	// 	}
 	// }
-
-	return nil
 }
 
 type Mode int
@@ -143,6 +152,10 @@ type Mode int
 const (
 	Live Mode = iota
 	IEMArchive
+	UGCUpdate
+	Verification
+	CAPIngest
+	Migrate
 )
 
 func main() {
@@ -158,6 +171,14 @@ func main() {
 			mode = Live
 		case "--iem":
 			mode = IEMArchive
+		case "--ugc-update":
+			mode = UGCUpdate
+		case "--verify":
+			mode = Verification
+		case "--cap":
+			mode = CAPIngest
+		case "--migrate":
+			mode = Migrate
 		}
 	}
 
@@ -166,6 +187,37 @@ func main() {
 	// 	log.Fatal("Error loading .env file")
 	// }
 
+	if mode == UGCUpdate {
+		if err := RunUGCUpdate(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if mode == Verification {
+		if err := db.SurrealInit(); err != nil {
+			log.Fatalf("Failed to connect to DB: %s", err.Error())
+		}
+		if err := RunVerification(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if mode == Migrate {
+		if err := db.SurrealInit(); err != nil {
+			log.Fatalf("Failed to connect to DB: %s", err.Error())
+		}
+		if err := db.MigrateTextProductAWIPS(); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Migrated the text product AWIPS identifiers\n")
+		return
+	}
+
+	loadUGCReference()
+	loadHazardCatalog()
+
 	if mode == Live {
 		for db.SurrealInit() != nil {
 			log.Printf("Failed to connect to DB: %s\nTrying again in 30 seconds\n\n", err.Error())
@@ -179,6 +231,15 @@ func main() {
 			time.Sleep(30 * time.Second)
 		}
 	}
+	if mode == CAPIngest {
+		for db.SurrealInit() != nil {
+			log.Printf("Failed to connect to DB. Trying again in 30 seconds\n\n")
+			time.Sleep(30 * time.Second)
+		}
+		if err := RunCAPIngest(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+	}
 	if mode == IEMArchive {
 		if db.SurrealInit() != nil {
 			log.Fatalf("Failed to connect to DB: %s", err.Error())
@@ -0,0 +1,427 @@
+package parsers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/util"
+)
+
+const (
+	CAPNamespace  = "urn:oasis:names:tc:emergency:cap:1.2"
+	capTimeLayout = "2006-01-02T15:04:05-07:00"
+)
+
+// The sender put on every generated alert. Set it to identify the feed to downstream systems
+var CAPSender = "nwws-go"
+
+// The state FIPS codes used to build the SAME geocodes of counties
+var stateFIPS = map[string]string{
+	"AL": "01", "AK": "02", "AZ": "04", "AR": "05", "CA": "06", "CO": "08", "CT": "09", "DE": "10",
+	"DC": "11", "FL": "12", "GA": "13", "HI": "15", "ID": "16", "IL": "17", "IN": "18", "IA": "19",
+	"KS": "20", "KY": "21", "LA": "22", "ME": "23", "MD": "24", "MA": "25", "MI": "26", "MN": "27",
+	"MS": "28", "MO": "29", "MT": "30", "NE": "31", "NV": "32", "NH": "33", "NJ": "34", "NM": "35",
+	"NY": "36", "NC": "37", "ND": "38", "OH": "39", "OK": "40", "OR": "41", "PA": "42", "RI": "44",
+	"SC": "45", "SD": "46", "TN": "47", "TX": "48", "UT": "49", "VT": "50", "VA": "51", "WA": "53",
+	"WV": "54", "WI": "55", "WY": "56", "AS": "60", "GU": "66", "MP": "69", "PR": "72", "VI": "78",
+}
+
+type CAPValue struct {
+	ValueName string `xml:"valueName"`
+	Value     string `xml:"value"`
+}
+
+type CAPArea struct {
+	AreaDesc string     `xml:"areaDesc"`
+	Polygon  []string   `xml:"polygon,omitempty"`
+	Geocode  []CAPValue `xml:"geocode,omitempty"`
+}
+
+// The elements are in the order the CAP 1.2 schema requires
+type CAPInfo struct {
+	Language     string     `xml:"language"`
+	Category     []string   `xml:"category"`
+	Event        string     `xml:"event"`
+	ResponseType []string   `xml:"responseType,omitempty"`
+	Urgency      string     `xml:"urgency"`
+	Severity     string     `xml:"severity"`
+	Certainty    string     `xml:"certainty"`
+	EventCode    []CAPValue `xml:"eventCode,omitempty"`
+	Effective    string     `xml:"effective,omitempty"`
+	Onset        string     `xml:"onset,omitempty"`
+	Expires      string     `xml:"expires,omitempty"`
+	SenderName   string     `xml:"senderName,omitempty"`
+	Headline     string     `xml:"headline,omitempty"`
+	Description  string     `xml:"description,omitempty"`
+	Instruction  string     `xml:"instruction,omitempty"`
+	Parameter    []CAPValue `xml:"parameter,omitempty"`
+	Area         []CAPArea  `xml:"area"`
+}
+
+type CAPAlert struct {
+	XMLName    xml.Name  `xml:"urn:oasis:names:tc:emergency:cap:1.2 alert"`
+	Identifier string    `xml:"identifier"`
+	Sender     string    `xml:"sender"`
+	Sent       string    `xml:"sent"`
+	Status     string    `xml:"status"`
+	MsgType    string    `xml:"msgType"`
+	Scope      string    `xml:"scope"`
+	Code       []string  `xml:"code,omitempty"`
+	References string    `xml:"references,omitempty"`
+	Info       []CAPInfo `xml:"info"`
+}
+
+// A previously sent alert that a later one refers to
+type CAPReference struct {
+	Sender     string
+	Identifier string
+	Sent       time.Time
+}
+
+func (r CAPReference) String() string {
+	return r.Sender + "," + r.Identifier + "," + r.Sent.UTC().Format(capTimeLayout)
+}
+
+/*
+CAPIdentifier gives the identifier of the alert for a segment. It is the same every time so later alerts can refer
+back to it. A product can have several segments for one event so the UGC is hashed in as well.
+*/
+func CAPIdentifier(segment VTECSegment) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(segment.UGC.Original))
+	return fmt.Sprintf("%s.%s.%s.%s.%s.%s.%s.%08x", CAPSender, segment.VTEC.WFO, segment.VTEC.Phenomena, segment.VTEC.Significance,
+		util.PadZero(fmt.Sprint(segment.VTEC.ETN), 4), segment.VTEC.Action, segment.Issued.UTC().Format("20060102150405"), hash.Sum32())
+}
+
+// CAPReferenceTo gives the reference a later alert uses to point back at the alert for a segment
+func CAPReferenceTo(segment VTECSegment) CAPReference {
+	return CAPReference{
+		Sender:     CAPSender,
+		Identifier: CAPIdentifier(segment),
+		Sent:       segment.Issued,
+	}
+}
+
+// CAPEventName gives the name of the hazard, e.g. Tornado Warning
+func CAPEventName(phenomena string, significance string) string {
+	return GetHazardCatalog().Name(phenomena, significance)
+}
+
+func capMsgType(action string) string {
+	switch action {
+	case "NEW", "ROU":
+		return "Alert"
+	case "CAN", "EXP", "UPG":
+		return "Cancel"
+	}
+	return "Update"
+}
+
+func capStatus(vtecType string) string {
+	switch vtecType {
+	case "T":
+		return "Test"
+	case "E", "X":
+		// Experimental products shouldn't be acted on
+		return "Draft"
+	}
+	return "Actual"
+}
+
+// Work out the urgency, severity and certainty the way the NWS does for each significance
+func capUrgencySeverityCertainty(segment VTECSegment) (string, string, string) {
+	switch segment.VTEC.Action {
+	case "CAN", "EXP", "UPG":
+		return "Past", "Minor", "Observed"
+	}
+
+	urgency := "Expected"
+	if segment.VTEC.Start != nil && segment.VTEC.Start.After(segment.Issued) {
+		urgency = "Future"
+	}
+
+	tags := segment.HazardTags
+	switch segment.VTEC.Significance {
+	case "W":
+		if urgency == "Expected" {
+			urgency = "Immediate"
+		}
+		severity := "Severe"
+		if segment.Emergency || tags.TornadoDamage == "CATASTROPHIC" || tags.ThunderstormDamage == "DESTRUCTIVE" {
+			severity = "Extreme"
+		}
+		certainty := "Likely"
+		if tags.Tornado == "OBSERVED" || tags.HailThreat == "OBSERVED" || tags.WindThreat == "OBSERVED" || segment.Emergency {
+			certainty = "Observed"
+		}
+		return urgency, severity, certainty
+	case "A":
+		severity := "Severe"
+		if segment.PDS {
+			severity = "Extreme"
+		}
+		return urgency, severity, "Possible"
+	case "Y":
+		return urgency, "Moderate", "Likely"
+	case "S":
+		return urgency, "Minor", "Observed"
+	}
+	return urgency, "Unknown", "Unknown"
+}
+
+func capResponseType(segment VTECSegment) []string {
+	switch segment.VTEC.Action {
+	case "CAN", "EXP", "UPG":
+		return []string{"AllClear"}
+	}
+	switch segment.VTEC.Significance {
+	case "W":
+		switch segment.VTEC.Phenomena {
+		case "TO", "SV", "EW", "HU", "TY", "SQ":
+			return []string{"Shelter"}
+		case "FF", "FA", "FL", "CF", "LS", "SS", "TS":
+			return []string{"Avoid"}
+		}
+		return []string{"Prepare"}
+	case "A":
+		return []string{"Prepare"}
+	}
+	return []string{"Monitor"}
+}
+
+// The parameters NWS CAP consumers expect, including the hazard tags
+func capParameters(product *Product, segment VTECSegment) []CAPValue {
+	parameters := []CAPValue{
+		{"AWIPSidentifier", product.AWIPS.Original},
+		{"WMOidentifier", product.WMO.Original},
+		{"WMOHEADER", product.WMO.Original},
+		{"EAS-ORG", "WXR"},
+		// NWS CAP keeps the slashes around the VTEC strings
+		{"VTEC", "/" + segment.VTEC.Original + "/"},
+		{"eventEndingTime", capTime(capExpires(segment))},
+	}
+	if segment.HVTEC != nil {
+		parameters = append(parameters, CAPValue{"HVTEC", "/" + segment.HVTEC.Original + "/"})
+	}
+
+	tags := segment.HazardTags
+	for _, tag := range []CAPValue{
+		{"tornadoDetection", tags.Tornado},
+		{"tornadoDamageThreat", tags.TornadoDamage},
+		{"thunderstormDamageThreat", tags.ThunderstormDamage},
+		{"hailThreat", tags.HailThreat},
+		{"maxHailSize", strings.TrimSuffix(strings.TrimSpace(tags.Hail), "IN")},
+		{"windThreat", tags.WindThreat},
+		{"maxWindGust", tags.Wind},
+		{"waterspoutDetection", tags.Waterspout},
+	} {
+		tag.Value = strings.TrimSpace(tag.Value)
+		if tag.Value != "" {
+			parameters = append(parameters, tag)
+		}
+	}
+
+	return parameters
+}
+
+func capTime(t time.Time) string {
+	return t.UTC().Format(capTimeLayout)
+}
+
+func capExpires(segment VTECSegment) time.Time {
+	if segment.VTEC.End != nil {
+		return *segment.VTEC.End
+	}
+	return segment.Expires
+}
+
+// The area of the segment, described by the zone names where they are known
+func capArea(segment VTECSegment) CAPArea {
+	area := CAPArea{}
+
+	names := []string{}
+	if segment.Zones != nil {
+		for _, zone := range segment.Zones.Zones {
+			names = append(names, zone.Name+", "+zone.State)
+		}
+	}
+	if len(names) == 0 {
+		names = segment.UGC.Codes()
+	}
+	area.AreaDesc = strings.Join(names, "; ")
+
+	if segment.Polygon != nil && len(segment.Polygon.Coordinates) > 0 {
+		ring := closeRing(segment.Polygon.Coordinates[0])
+		if len(ring) >= 4 {
+			points := []string{}
+			for _, point := range ring {
+				points = append(points, fmt.Sprintf("%.2f,%.2f", point[1], point[0]))
+			}
+			area.Polygon = append(area.Polygon, strings.Join(points, " "))
+		}
+	}
+
+	for _, state := range segment.UGC.States {
+		for _, zone := range state.Zones {
+			code := state.Name + state.Type + zone
+			area.Geocode = append(area.Geocode, CAPValue{"UGC", code})
+			if fips, ok := stateFIPS[state.Name]; ok && state.Type == "C" {
+				area.Geocode = append(area.Geocode, CAPValue{"SAME", "0" + fips + zone})
+			}
+		}
+	}
+
+	return area
+}
+
+// The body of the segment, between the VTEC and the call to action
+func capDescription(segment VTECSegment) string {
+	text := segment.Original
+	if index := strings.LastIndex(text, segment.VTEC.Original); index >= 0 {
+		text = text[index+len(segment.VTEC.Original):]
+		text = strings.TrimPrefix(text, "/")
+	}
+	if segment.HVTEC != nil {
+		if index := strings.Index(text, segment.HVTEC.Original); index >= 0 {
+			text = strings.TrimPrefix(text[index+len(segment.HVTEC.Original):], "/")
+		}
+	}
+	for _, end := range []string{"PRECAUTIONARY/PREPAREDNESS ACTIONS...", "&&", "LAT...LON"} {
+		if index := strings.Index(text, end); index >= 0 {
+			text = text[:index]
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// The call to action, if the product has one
+func capInstruction(text string) string {
+	instructionRegexp := regexp.MustCompile(`(?s:PRECAUTIONARY/PREPAREDNESS ACTIONS\.\.\.(.*?)(&&|$))`)
+	match := instructionRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(match[1])
+}
+
+/*
+NewCAPAlert builds a CAP 1.2 alert for a segment of a VTEC product. references are the earlier alerts for the
+same event, which updates and cancellations must refer to.
+*/
+func NewCAPAlert(product *Product, segment VTECSegment, references []CAPReference) (*CAPAlert, error) {
+	if product == nil {
+		return nil, errors.New("cannot build a CAP alert without the product")
+	}
+
+	msgType := capMsgType(segment.VTEC.Action)
+	if msgType != "Alert" && len(references) == 0 {
+		return nil, fmt.Errorf("%s for %s needs references to the earlier alerts", msgType, segment.VTEC.Original)
+	}
+
+	refs := []string{}
+	for _, reference := range references {
+		refs = append(refs, reference.String())
+	}
+
+	event := CAPEventName(segment.VTEC.Phenomena, segment.VTEC.Significance)
+	urgency, severity, certainty := capUrgencySeverityCertainty(segment)
+
+	onset := segment.Issued
+	if segment.VTEC.Start != nil {
+		onset = *segment.VTEC.Start
+	}
+	expires := capExpires(segment)
+
+	eventCodes := []CAPValue{
+		{"NationalWeatherService", segment.VTEC.Phenomena + segment.VTEC.Significance},
+	}
+	if hazard := GetHazardCatalog().Hazard(segment.VTEC.Phenomena, segment.VTEC.Significance); hazard != nil {
+		eventCodes[0].Value = hazard.CAPEvent
+		if hazard.SAME != "" {
+			eventCodes = append([]CAPValue{{"SAME", hazard.SAME}}, eventCodes...)
+		}
+	}
+
+	headline := fmt.Sprintf("%s issued %s until %s by NWS %s", event, capTime(segment.Issued), capTime(expires), segment.VTEC.WFO)
+	switch msgType {
+	case "Cancel":
+		headline = fmt.Sprintf("The %s has been cancelled by NWS %s", event, segment.VTEC.WFO)
+	case "Update":
+		headline = fmt.Sprintf("%s updated %s until %s by NWS %s", event, capTime(segment.Issued), capTime(expires), segment.VTEC.WFO)
+	}
+
+	alert := CAPAlert{
+		Identifier: CAPIdentifier(segment),
+		Sender:     CAPSender,
+		Sent:       capTime(segment.Issued),
+		Status:     capStatus(segment.VTEC.Type),
+		MsgType:    msgType,
+		Scope:      "Public",
+		Code:       []string{"IPAWSv1.0"},
+		References: strings.Join(refs, " "),
+		Info: []CAPInfo{
+			{
+				Language:     "en-US",
+				Category:     []string{"Met"},
+				Event:        event,
+				ResponseType: capResponseType(segment),
+				Urgency:      urgency,
+				Severity:     severity,
+				Certainty:    certainty,
+				EventCode:    eventCodes,
+				Effective:    capTime(segment.Issued),
+				Onset:        capTime(onset),
+				Expires:      capTime(expires),
+				SenderName:   "NWS " + segment.VTEC.WFO,
+				Headline:     headline,
+				Description:  capDescription(segment),
+				Instruction:  capInstruction(segment.Original),
+				Parameter:    capParameters(product, segment),
+				Area:         []CAPArea{capArea(segment)},
+			},
+		},
+	}
+
+	return &alert, nil
+}
+
+// XML gives the alert as a CAP 1.2 document
+func (a *CAPAlert) XML() ([]byte, error) {
+	out, err := xml.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+/*
+CAPAlerts builds an alert for every segment of the product. previous gives the earlier alerts of an event
+and is needed for anything that isn't a new event.
+*/
+func (p *VTECProduct) CAPAlerts(previous func(segment VTECSegment) ([]CAPReference, error)) ([]*CAPAlert, error) {
+	alerts := []*CAPAlert{}
+	for _, segment := range p.Segments {
+		references := []CAPReference{}
+		if capMsgType(segment.VTEC.Action) != "Alert" {
+			if previous == nil {
+				return nil, errors.New("no way to find the earlier alerts for " + segment.VTEC.Original)
+			}
+			r, err := previous(segment)
+			if err != nil {
+				return nil, err
+			}
+			references = r
+		}
+		alert, err := NewCAPAlert(p.Product, segment, references)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
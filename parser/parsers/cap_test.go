@@ -0,0 +1,112 @@
+package parsers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testVTECProduct(t *testing.T, name string) *VTECProduct {
+	t.Helper()
+	text, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := NewAWIPSProduct(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vtecProduct, err := product.VTECProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vtecProduct
+}
+
+// Validate the alert against the CAP 1.2 schema with xmllint
+func validateCAP(t *testing.T, alert *CAPAlert) {
+	t.Helper()
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint is needed to validate against the CAP schema")
+	}
+
+	out, err := alert.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "alert.xml")
+	if err = os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := exec.Command(xmllint, "--noout", "--schema", "testdata/CAP-v1.2.xsd", path).CombinedOutput()
+	if err != nil {
+		t.Errorf("%s does not validate: %s\n%s", alert.Identifier, string(result), string(out))
+	}
+}
+
+func capParameter(alert *CAPAlert, name string) string {
+	for _, parameter := range alert.Info[0].Parameter {
+		if parameter.ValueName == name {
+			return parameter.Value
+		}
+	}
+	return ""
+}
+
+func TestCAPAlertsValidate(t *testing.T) {
+	warning := testVTECProduct(t, "TORDMX.txt")
+	alerts, err := warning.CAPAlerts(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+
+	alert := alerts[0]
+	info := alert.Info[0]
+	if alert.MsgType != "Alert" || alert.References != "" {
+		t.Errorf("got msgType %s with references %q, want a new alert", alert.MsgType, alert.References)
+	}
+	if info.Event != "Tornado Warning" || info.Urgency != "Immediate" || info.Severity != "Severe" {
+		t.Errorf("got %s, %s, %s", info.Event, info.Urgency, info.Severity)
+	}
+	if capParameter(alert, "VTEC") != "/O.NEW.KDMX.TO.W.0045.240521T2012Z-240521T2100Z/" || capParameter(alert, "EAS-ORG") != "WXR" || capParameter(alert, "WMOHEADER") == "" {
+		t.Errorf("missing parameters in %v", info.Parameter)
+	}
+	if capParameter(alert, "tornadoDetection") != "RADAR INDICATED" {
+		t.Errorf("got tornadoDetection %q", capParameter(alert, "tornadoDetection"))
+	}
+	if len(info.Area) != 1 || len(info.Area[0].Polygon) != 1 || len(info.Area[0].Geocode) != 4 {
+		t.Errorf("got area %+v", info.Area)
+	}
+	validateCAP(t, alert)
+
+	// The cancellation has to refer back to the warning
+	cancel := testVTECProduct(t, "TORDMX-CAN.txt")
+	if _, err = cancel.CAPAlerts(nil); err == nil {
+		t.Error("a cancellation without references should fail")
+	}
+	alerts, err = cancel.CAPAlerts(func(segment VTECSegment) ([]CAPReference, error) {
+		return []CAPReference{CAPReferenceTo(warning.Segments[0])}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled := alerts[0]
+	if cancelled.MsgType != "Cancel" {
+		t.Errorf("got msgType %s, want Cancel", cancelled.MsgType)
+	}
+	if !strings.Contains(cancelled.References, alert.Identifier) || !strings.HasSuffix(cancelled.References, alert.Sent) {
+		t.Errorf("references %q do not point at %s sent %s", cancelled.References, alert.Identifier, alert.Sent)
+	}
+	if cancelled.Info[0].Urgency != "Past" || cancelled.Info[0].ResponseType[0] != "AllClear" {
+		t.Errorf("got urgency %s and response %v", cancelled.Info[0].Urgency, cancelled.Info[0].ResponseType)
+	}
+	validateCAP(t, cancelled)
+}
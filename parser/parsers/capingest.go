@@ -0,0 +1,414 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The sources a VTEC product can come from
+const (
+	SourceNWWS = "nwws"
+	SourceCAP  = "cap"
+)
+
+type capAtomFeed struct {
+	Entries []struct {
+		ID    string `xml:"id"`
+		Sent  string `xml:"sent"` // cap:sent, which the NWS feed carries on each entry
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// CAPDocumentType returns the name of the root element, alert for a CAP alert and feed for an ATOM feed
+func CAPDocumentType(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", errors.New("empty XML document")
+		}
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// An entry of an ATOM feed of CAP alerts
+type CAPAtomEntry struct {
+	Link       string
+	Identifier string    // The CAP identifier, the last part of the entry ID
+	Sent       time.Time // Zero if the feed doesn't give it
+}
+
+// ParseCAPAtom returns the link to the full CAP alert for every entry of an ATOM feed, such as api.weather.gov/alerts/active.atom
+func ParseCAPAtom(data []byte) ([]CAPAtomEntry, error) {
+	feed := capAtomFeed{}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	entries := []CAPAtomEntry{}
+	for _, e := range feed.Entries {
+		entry := CAPAtomEntry{
+			Link:       e.ID,
+			Identifier: e.ID[strings.LastIndex(e.ID, "/")+1:],
+		}
+		for _, l := range e.Links {
+			if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+				entry.Link = l.Href
+				break
+			}
+		}
+		if sent, err := time.Parse(time.RFC3339, strings.TrimSpace(e.Sent)); err == nil {
+			entry.Sent = sent.UTC()
+		}
+		if entry.Link != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func ParseCAPAlert(data []byte) (*CAPAlert, error) {
+	alert := CAPAlert{}
+	if err := xml.Unmarshal(data, &alert); err != nil {
+		return nil, err
+	}
+	if alert.Identifier == "" {
+		return nil, errors.New("CAP alert has no identifier")
+	}
+	return &alert, nil
+}
+
+// The values of every parameter with the name
+func capValues(values []CAPValue, name string) []string {
+	found := []string{}
+	for _, v := range values {
+		if v.ValueName == name {
+			found = append(found, strings.TrimSpace(v.Value))
+		}
+	}
+	return found
+}
+
+func capValue(values []CAPValue, name string) string {
+	found := capValues(values, name)
+	if len(found) == 0 {
+		return ""
+	}
+	return found[0]
+}
+
+// Build a UGC from a list of codes, grouping them by state and type the way they are in the products
+func ugcFromCodes(codes []string, expires time.Time) (*UGC, error) {
+	sort.Strings(codes)
+	ugc := UGC{
+		States:  []State{},
+		Expires: expires,
+	}
+	parts := []string{}
+	for _, code := range codes {
+		if len(code) != 6 {
+			return nil, errors.New("invalid UGC code " + code)
+		}
+		name := code[:2]
+		t := code[2:3]
+		last := len(ugc.States) - 1
+		if last < 0 || ugc.States[last].Name != name || ugc.States[last].Type != t {
+			ugc.States = append(ugc.States, State{
+				Name:  name,
+				Type:  t,
+				Class: ugcClass(name, t),
+				Zones: []string{},
+			})
+			last++
+			parts = append(parts, code)
+		} else {
+			parts = append(parts, code[3:])
+		}
+		ugc.States[last].Zones = append(ugc.States[last].Zones, code[3:])
+	}
+	ugc.Original = strings.Join(append(parts, expires.UTC().Format("021504")), "-")
+	return &ugc, nil
+}
+
+// Parse a CAP polygon, "lat,lon lat,lon ...", into a GeoJSON polygon
+func capPolygon(text string) (*PolygonFeature, error) {
+	ring := [][2]float64{}
+	for _, pair := range strings.Fields(text) {
+		values := strings.Split(pair, ",")
+		if len(values) != 2 {
+			return nil, errors.New("invalid CAP polygon point " + pair)
+		}
+		lat, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, errors.New("invalid CAP polygon latitude " + values[0])
+		}
+		lon, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, errors.New("invalid CAP polygon longitude " + values[1])
+		}
+		ring = append(ring, [2]float64{lon, lat})
+	}
+	if len(ring) < 4 {
+		return nil, errors.New("CAP polygon has too few points")
+	}
+	return &PolygonFeature{
+		Type:        "Polygon",
+		Coordinates: [][][2]float64{closeRing(ring)},
+	}, nil
+}
+
+func capParseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+/*
+VTECProduct maps the alert into the same model as the products from NWWS-OI. The alert only carries the
+text of its description so the product is rebuilt from the WMO and AWIPS identifiers in its parameters.
+Alerts without VTEC give nil.
+*/
+func (a *CAPAlert) VTECProduct() (*VTECProduct, error) {
+	sent, err := capParseTime(a.Sent)
+	if err != nil || sent == nil {
+		return nil, errors.New("could not parse CAP sent time " + a.Sent)
+	}
+	// The products are only issued to the minute
+	issued := sent.Truncate(time.Minute)
+
+	vtecProduct := VTECProduct{
+		Source:   SourceCAP,
+		Segments: []VTECSegment{},
+	}
+
+	for _, info := range a.Info {
+		vtecStrings := capValues(info.Parameter, "VTEC")
+		if len(vtecStrings) == 0 {
+			continue
+		}
+
+		if vtecProduct.Product == nil {
+			awipsString := capValue(info.Parameter, "AWIPSidentifier")
+			if len(awipsString) < 4 {
+				return nil, errors.New("CAP alert " + a.Identifier + " has no AWIPS identifier")
+			}
+			awips := AWIPS{
+				Original: awipsString,
+				Product:  awipsString[:3],
+				WFO:      awipsString[3:],
+			}
+			wmoString := capValue(info.Parameter, "WMOidentifier")
+			if wmoString == "" {
+				wmoString = capValue(info.Parameter, "WMOHEADER")
+			}
+			wmo, err := ParseWMO(wmoString, issued)
+			if err != nil {
+				return nil, fmt.Errorf("CAP alert %s: %s", a.Identifier, err.Error())
+			}
+			vtecProduct.Product = &Product{
+				ID:      "CAP" + awips.Original + issued.Format("200601021504"),
+				Group:   awips.WFO + awips.Product + issued.Format("200601021504"),
+				Text:    info.Description,
+				WMO:     wmo,
+				AWIPS:   awips,
+				Issued:  issued,
+				Source:  SourceCAP,
+				WFO:     awips.WFO,
+				Product: awips.Product,
+			}
+		}
+
+		expires, err := capParseTime(info.Expires)
+		if err != nil {
+			return nil, errors.New("could not parse CAP expires time " + info.Expires)
+		}
+		if expires == nil {
+			expires = &issued
+		}
+
+		codes := []string{}
+		var polygon *PolygonFeature
+		for _, area := range info.Area {
+			codes = append(codes, capValues(area.Geocode, "UGC")...)
+			if polygon == nil && len(area.Polygon) > 0 {
+				polygon, err = capPolygon(area.Polygon[0])
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		ugc, err := ugcFromCodes(codes, *expires)
+		if err != nil {
+			return nil, err
+		}
+
+		var hvtec *HVTEC
+		if h := capValue(info.Parameter, "HVTEC"); h != "" {
+			hvtecs, err := ParseHVTEC(h)
+			if err != nil {
+				return nil, err
+			}
+			if len(hvtecs) > 0 {
+				hvtec = &hvtecs[0]
+			}
+		}
+
+		tags := HazardTags{
+			Tornado:            capValue(info.Parameter, "tornadoDetection"),
+			TornadoDamage:      capValue(info.Parameter, "tornadoDamageThreat"),
+			ThunderstormDamage: capValue(info.Parameter, "thunderstormDamageThreat"),
+			HailThreat:         capValue(info.Parameter, "hailThreat"),
+			Hail:               capValue(info.Parameter, "maxHailSize"),
+			WindThreat:         capValue(info.Parameter, "windThreat"),
+			Wind:               capValue(info.Parameter, "maxWindGust"),
+			Waterspout:         capValue(info.Parameter, "waterspoutDetection"),
+		}
+
+		var zones *UGCResolution
+		if ref := GetUGCReference(); ref != nil {
+			resolution := ref.Resolve(*ugc, issued)
+			zones = &resolution
+		}
+
+		// Each info is one segment of the product, holding every VTEC of it
+		vtecs := []PVTEC{}
+		for _, v := range vtecStrings {
+			parsed, err := ParsePVTEC("/"+strings.Trim(v, "/")+"/", issued, *ugc)
+			if err != nil {
+				return nil, err
+			}
+			vtecs = append(vtecs, parsed...)
+		}
+
+		for _, vtec := range vtecs {
+			vtecProduct.Segments = append(vtecProduct.Segments, VTECSegment{
+				Original:     info.Description,
+				Start:        vtec.Start,
+				End:          vtec.End,
+				Issued:       issued,
+				Expires:      ugc.Expires,
+				EventNumber:  vtec.ETN,
+				Action:       vtec.Action,
+				Phenomena:    vtec.Phenomena,
+				Significance: vtec.Significance,
+				Polygon:      polygon,
+				VTEC:         vtec,
+				SegmentVTEC:  vtecs,
+				HVTEC:        hvtec,
+				UGC:          *ugc,
+				Zones:        zones,
+				HazardTags:   tags,
+				Emergency:    strings.Contains(strings.ToUpper(info.Description), "EMERGENCY") && vtec.Significance == "W",
+				PDS:          strings.Contains(strings.ToUpper(info.Description), "PARTICULARLY DANGEROUS SITUATION"),
+				WFO:          vtec.WFO,
+			})
+		}
+	}
+
+	if vtecProduct.Product == nil {
+		return nil, nil
+	}
+
+	return &vtecProduct, nil
+}
+
+// How far apart the CAP and NWWS-OI copies of a segment can be issued, as the CAP can be sent a little after the product
+const SegmentMatchWindow = 5 * time.Minute
+
+/*
+SameSegment returns true if two segments, such as from CAP and NWWS-OI, are copies of each other. They must be
+the same action on the same event, issued within window of each other and share at least one zone. The VTEC
+strings are not compared whole as the sources can differ on the times in them.
+*/
+func SameSegment(a VTECSegment, b VTECSegment, window time.Duration) bool {
+	if vtecEventKey(a.VTEC) != vtecEventKey(b.VTEC) || a.VTEC.Action != b.VTEC.Action {
+		return false
+	}
+	if a.Issued.Sub(b.Issued) > window || b.Issued.Sub(a.Issued) > window {
+		return false
+	}
+	codes := map[string]bool{}
+	for _, code := range a.UGC.Codes() {
+		codes[code] = true
+	}
+	for _, code := range b.UGC.Codes() {
+		if codes[code] {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+CompareSegments lists the differences between the same VTEC segment from two sources, such as CAP and
+NWWS-OI. The polygons are compared to two decimal places as that is all the products give.
+*/
+func CompareSegments(a VTECSegment, b VTECSegment) []string {
+	differences := []string{}
+
+	if a.VTEC.Original != b.VTEC.Original {
+		differences = append(differences, fmt.Sprintf("VTEC %s != %s", a.VTEC.Original, b.VTEC.Original))
+	}
+
+	endA := a.Expires
+	if a.VTEC.End != nil {
+		endA = *a.VTEC.End
+	}
+	endB := b.Expires
+	if b.VTEC.End != nil {
+		endB = *b.VTEC.End
+	}
+	if !endA.Equal(endB) {
+		differences = append(differences, fmt.Sprintf("end %s != %s", endA.Format(time.RFC3339), endB.Format(time.RFC3339)))
+	}
+
+	codesA := a.UGC.Codes()
+	codesB := b.UGC.Codes()
+	sort.Strings(codesA)
+	sort.Strings(codesB)
+	if strings.Join(codesA, ",") != strings.Join(codesB, ",") {
+		differences = append(differences, fmt.Sprintf("UGC %s != %s", strings.Join(codesA, ","), strings.Join(codesB, ",")))
+	}
+
+	switch {
+	case (a.Polygon == nil) != (b.Polygon == nil):
+		differences = append(differences, "polygon missing from one source")
+	case a.Polygon != nil && len(a.Polygon.Coordinates) > 0 && len(b.Polygon.Coordinates) > 0:
+		ringA := openRing(a.Polygon.Coordinates[0])
+		ringB := openRing(b.Polygon.Coordinates[0])
+		same := len(ringA) == len(ringB)
+		for i := 0; same && i < len(ringA); i++ {
+			if fmt.Sprintf("%.2f,%.2f", ringA[i][0], ringA[i][1]) != fmt.Sprintf("%.2f,%.2f", ringB[i][0], ringB[i][1]) {
+				same = false
+			}
+		}
+		if !same {
+			differences = append(differences, "polygon differs")
+		}
+	}
+
+	return differences
+}
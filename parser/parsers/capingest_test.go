@@ -0,0 +1,182 @@
+package parsers
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCAPAlert(t *testing.T, name string) *CAPAlert {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := ParseCAPAlert(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alert
+}
+
+func TestParseCAPAlert(t *testing.T) {
+	alert := testCAPAlert(t, "TORDMX-cap.xml")
+	if alert.MsgType != "Alert" || alert.Sent != "2024-05-21T15:12:41-05:00" {
+		t.Errorf("got %s sent %s", alert.MsgType, alert.Sent)
+	}
+	if len(alert.Info) != 1 || alert.Info[0].Event != "Tornado Warning" {
+		t.Fatalf("got info %+v", alert.Info)
+	}
+	if vtec := capValue(alert.Info[0].Parameter, "VTEC"); vtec != "/O.NEW.KDMX.TO.W.0045.240521T2012Z-240521T2100Z/" {
+		t.Errorf("got VTEC %s", vtec)
+	}
+
+	if _, err := ParseCAPAlert([]byte(`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"></alert>`)); err == nil {
+		t.Error("an alert without an identifier should fail")
+	}
+}
+
+func TestCAPAlertVTECProduct(t *testing.T) {
+	product, err := testCAPAlert(t, "TORDMX-cap.xml").VTECProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Source != SourceCAP || product.Product.AWIPS.Original != "TORDMX" {
+		t.Errorf("got source %s and AWIPS %s", product.Source, product.Product.AWIPS.Original)
+	}
+	if len(product.Segments) != 1 {
+		t.Fatalf("got %d segments, want 1", len(product.Segments))
+	}
+
+	segment := product.Segments[0]
+	if !segment.Issued.Equal(testTime(t, "2024-05-21T20:12:00Z")) {
+		t.Errorf("issued %s, want the sent time to the minute", segment.Issued)
+	}
+	if segment.VTEC.Action != "NEW" || segment.VTEC.ETN != 45 || len(segment.SegmentVTEC) != 1 {
+		t.Errorf("got VTEC %+v", segment.VTEC)
+	}
+	if codes := strings.Join(segment.UGC.Codes(), ","); codes != "IAC015,IAC169" {
+		t.Errorf("got UGC %s", codes)
+	}
+	if segment.HazardTags.Tornado != "RADAR INDICATED" {
+		t.Errorf("got tornado tag %q", segment.HazardTags.Tornado)
+	}
+
+	// The NWWS-OI changed of the same warning
+	nwws := testVTECProduct(t, "TORDMX.txt").Segments[0]
+	if !SameSegment(segment, nwws, SegmentMatchWindow) {
+		t.Error("the CAP and NWWS-OI copies should be the same segment")
+	}
+	if differences := CompareSegments(segment, nwws); len(differences) != 0 {
+		t.Errorf("got differences %v", differences)
+	}
+}
+
+func TestCAPAlertUpgradePair(t *testing.T) {
+	alert := testCAPAlert(t, "TORDMX-cap.xml")
+	alert.Info[0].Parameter = append(alert.Info[0].Parameter, CAPValue{"VTEC", "/O.UPG.KDMX.SV.W.0120.000000T0000Z-240521T2030Z/"})
+
+	product, err := alert.VTECProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(product.Segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(product.Segments))
+	}
+
+	// The pair comes from the VTEC kept on the segment, not from the description
+	warning := NewVTECEvent(product.Segments[0].VTEC)
+	zones, err := warning.Apply(product.Segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zones[0].UpgradedFrom != "DMX.SV.W.0120" {
+		t.Errorf("upgraded from %q, want DMX.SV.W.0120", zones[0].UpgradedFrom)
+	}
+}
+
+func TestSameSegment(t *testing.T) {
+	nwws := testVTECProduct(t, "TORDMX.txt").Segments[0]
+
+	tests := []struct {
+		name   string
+		change func(segment *VTECSegment)
+		want   bool
+	}{
+		{"identical", func(segment *VTECSegment) {}, true},
+		{"sent a few minutes later", func(segment *VTECSegment) { segment.Issued = segment.Issued.Add(3 * time.Minute) }, true},
+		{"issued outside the window", func(segment *VTECSegment) { segment.Issued = segment.Issued.Add(10 * time.Minute) }, false},
+		{"a different end time", func(segment *VTECSegment) {
+			end := segment.VTEC.End.Add(15 * time.Minute)
+			segment.VTEC.End = &end
+		}, true},
+		{"a different action", func(segment *VTECSegment) { segment.VTEC.Action = "CON" }, false},
+		{"a different event", func(segment *VTECSegment) { segment.VTEC.ETN = 46 }, false},
+		{"no zones in common", func(segment *VTECSegment) {
+			segment.UGC = UGC{States: []State{{Name: "IA", Type: "C", Zones: []string{"079"}}}}
+		}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			other := nwws
+			test.change(&other)
+			if got := SameSegment(nwws, other, SegmentMatchWindow); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareSegments(t *testing.T) {
+	nwws := testVTECProduct(t, "TORDMX.txt").Segments[0]
+
+	changed := nwws
+	end := nwws.VTEC.End.Add(15 * time.Minute)
+	changed.VTEC.End = &end
+	changed.UGC = UGC{States: []State{{Name: "IA", Type: "C", Zones: []string{"015"}}}}
+	changed.Polygon = &PolygonFeature{Type: "Polygon", Coordinates: [][][2]float64{{{-94, 41.95}, {-93.4, 42.15}, {-93.3, 41.85}, {-94, 41.95}}}}
+
+	differences := CompareSegments(changed, nwws)
+	for _, want := range []string{"end ", "UGC ", "polygon differs"} {
+		found := false
+		for _, difference := range differences {
+			if strings.HasPrefix(difference, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%v does not include %q", differences, want)
+		}
+	}
+}
+
+func TestParseCAPAtom(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:cap="urn:oasis:names:tc:emergency:cap:1.2">
+<entry>
+<id>https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.1a2b.001.1</id>
+<link href="https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.1a2b.001.1"/>
+<cap:sent>2024-05-21T16:02:00-05:00</cap:sent>
+</entry>
+<entry>
+<id>https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.3c4d.001.1</id>
+</entry>
+</feed>`
+
+	entries, err := ParseCAPAtom([]byte(feed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Identifier != "urn:oid:2.49.0.1.840.0.1a2b.001.1" || !entries[0].Sent.Equal(testTime(t, "2024-05-21T21:02:00Z")) {
+		t.Errorf("got entry %+v", entries[0])
+	}
+	// Without a link the ID is where the alert is
+	if entries[1].Link != "https://api.weather.gov/alerts/urn:oid:2.49.0.1.840.0.3c4d.001.1" || !entries[1].Sent.IsZero() {
+		t.Errorf("got entry %+v", entries[1])
+	}
+}
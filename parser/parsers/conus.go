@@ -0,0 +1,118 @@
+package parsers
+
+/*
+A simplified outline of the contiguous United States used to close and clip the SPC outlook contours.
+It runs clockwise from Cape Flattery, follows the Canadian border through the Great Lakes, down the
+Atlantic and Gulf coasts, along the Mexican border and back up the Pacific coast. The coasts sit a little
+offshore so contours drawn along the coastline aren't cut short.
+*/
+var CONUS = [][2]float64{
+	{-124.8, 48.4},
+	{-123.3, 48.3},
+	{-123.0, 49.0},
+	{-95.15, 49.0},
+	{-95.15, 49.4},
+	{-94.6, 48.7},
+	{-93.0, 48.6},
+	{-91.4, 48.05},
+	{-89.6, 48.0},
+	{-89.3, 48.1},
+	{-84.8, 46.9},
+	{-84.1, 46.5},
+	{-83.5, 46.0},
+	{-82.5, 45.3},
+	{-82.1, 43.0},
+	{-82.5, 42.6},
+	{-83.1, 42.0},
+	{-82.5, 41.7},
+	{-79.8, 42.5},
+	{-78.9, 42.9},
+	{-79.1, 43.5},
+	{-76.4, 43.6},
+	{-76.3, 44.2},
+	{-75.0, 44.9},
+	{-74.7, 45.0},
+	{-71.5, 45.0},
+	{-71.1, 45.3},
+	{-70.6, 45.7},
+	{-70.0, 46.7},
+	{-69.2, 47.45},
+	{-68.3, 47.35},
+	{-67.8, 47.1},
+	{-67.8, 45.7},
+	{-67.4, 45.2},
+	{-66.8, 44.8},
+	{-67.0, 44.4},
+	{-69.5, 43.5},
+	{-70.5, 42.7},
+	{-69.8, 41.6},
+	{-69.9, 41.1},
+	{-71.8, 40.9},
+	{-73.9, 40.3},
+	{-74.0, 39.6},
+	{-74.9, 38.8},
+	{-75.0, 38.3},
+	{-75.4, 37.5},
+	{-75.8, 36.8},
+	{-75.4, 35.2},
+	{-76.5, 34.5},
+	{-77.9, 33.7},
+	{-79.2, 33.0},
+	{-80.8, 31.9},
+	{-81.3, 30.7},
+	{-81.1, 29.7},
+	{-80.4, 28.4},
+	{-79.9, 26.7},
+	{-80.0, 25.3},
+	{-80.9, 24.5},
+	{-81.8, 24.3},
+	{-82.3, 24.5},
+	{-81.9, 26.1},
+	{-83.0, 27.8},
+	{-82.9, 29.0},
+	{-83.8, 29.8},
+	{-84.4, 29.6},
+	{-85.4, 29.5},
+	{-86.5, 30.2},
+	{-88.0, 30.1},
+	{-89.0, 30.0},
+	{-89.2, 28.9},
+	{-90.5, 28.9},
+	{-92.0, 29.4},
+	{-93.8, 29.5},
+	{-94.8, 29.1},
+	{-96.3, 28.1},
+	{-97.1, 27.2},
+	{-97.0, 25.9},
+	{-97.5, 25.8},
+	{-99.1, 26.4},
+	{-99.5, 27.4},
+	{-100.3, 28.2},
+	{-101.4, 29.7},
+	{-102.4, 29.7},
+	{-103.0, 28.9},
+	{-103.3, 28.9},
+	{-104.5, 29.6},
+	{-104.9, 30.5},
+	{-106.5, 31.7},
+	{-108.2, 31.7},
+	{-108.2, 31.3},
+	{-111.1, 31.3},
+	{-114.8, 32.45},
+	{-114.7, 32.7},
+	{-117.1, 32.45},
+	{-117.4, 33.1},
+	{-118.6, 33.3},
+	{-120.8, 34.3},
+	{-121.0, 35.4},
+	{-122.0, 36.2},
+	{-122.7, 37.5},
+	{-123.2, 37.9},
+	{-123.9, 39.0},
+	{-124.5, 40.4},
+	{-124.4, 41.9},
+	{-124.7, 42.8},
+	{-124.3, 44.0},
+	{-124.3, 46.3},
+	{-124.8, 48.4},
+}
@@ -0,0 +1,115 @@
+package parsers
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"sync"
+)
+
+/*
+ProductHandler claims the products it parses and stores. A product is claimed when its AWIPS identifier
+(e.g. SWOMCD) matches one of the AWIPS patterns, its TTAAII matches one of the WMO patterns and Match, if set,
+returns true. Patterns are globs, so TOR* claims every tornado warning, and an empty list matches everything.
+*/
+type ProductHandler struct {
+	Name     string
+	AWIPS    []string
+	WMO      []string
+	Match    func(p *Product) bool
+	Priority int // Higher priorities run first
+	// Stop the handlers of lower priority from running once this one has claimed the product. Those of the same priority still run
+	Exclusive bool
+	Handle    func(p *Product) error
+}
+
+func globsMatch(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims returns true if the handler should run on the product
+func (h *ProductHandler) Claims(p *Product) bool {
+	if !globsMatch(h.AWIPS, p.AWIPS.Original) || !globsMatch(h.WMO, p.WMO.Datatype) {
+		return false
+	}
+	return h.Match == nil || h.Match(p)
+}
+
+var productHandlersLock = &sync.Mutex{}
+
+var productHandlers = []ProductHandler{}
+
+// RegisterHandler adds a handler to those run on every product. Names must be unique
+func RegisterHandler(handler ProductHandler) error {
+	if handler.Name == "" {
+		return errors.New("product handler has no name")
+	}
+	if handler.Handle == nil {
+		return errors.New("product handler " + handler.Name + " has nothing to handle products with")
+	}
+	for _, pattern := range append(append([]string{}, handler.AWIPS...), handler.WMO...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New("product handler " + handler.Name + " has invalid pattern " + pattern)
+		}
+	}
+
+	productHandlersLock.Lock()
+	defer productHandlersLock.Unlock()
+
+	for _, h := range productHandlers {
+		if h.Name == handler.Name {
+			return errors.New("product handler " + handler.Name + " is already registered")
+		}
+	}
+	productHandlers = append(productHandlers, handler)
+	// Handlers of the same priority run in the order they were registered
+	sort.SliceStable(productHandlers, func(i, j int) bool {
+		return productHandlers[i].Priority > productHandlers[j].Priority
+	})
+
+	return nil
+}
+
+// HandlersFor lists the handlers that claim the product in the order they run
+func HandlersFor(p *Product) []ProductHandler {
+	productHandlersLock.Lock()
+	defer productHandlersLock.Unlock()
+
+	handlers := []ProductHandler{}
+	exclusive := false
+	exclusivePriority := 0
+	for _, h := range productHandlers {
+		// Handlers of the same priority as an exclusive one still run
+		if exclusive && h.Priority < exclusivePriority {
+			break
+		}
+		if !h.Claims(p) {
+			continue
+		}
+		handlers = append(handlers, h)
+		if h.Exclusive && !exclusive {
+			exclusive = true
+			exclusivePriority = h.Priority
+		}
+	}
+	return handlers
+}
+
+// RunHandlers runs every handler that claims the product. A handler failing does not stop the others
+func RunHandlers(p *Product) error {
+	errs := []error{}
+	for _, h := range HandlersFor(p) {
+		if err := h.Handle(p); err != nil {
+			errs = append(errs, errors.New(h.Name+": "+err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHandlersForExclusive(t *testing.T) {
+	productHandlersLock.Lock()
+	registered := productHandlers
+	productHandlers = []ProductHandler{}
+	productHandlersLock.Unlock()
+	defer func() {
+		productHandlersLock.Lock()
+		productHandlers = registered
+		productHandlersLock.Unlock()
+	}()
+
+	handle := func(p *Product) error { return nil }
+	for _, handler := range []ProductHandler{
+		{Name: "vtec", Priority: 0, Handle: handle},
+		{Name: "watch", AWIPS: []string{"SEL*"}, Priority: 100, Exclusive: true, Handle: handle},
+		{Name: "wcn", AWIPS: []string{"WCN*"}, Priority: 100, Handle: handle},
+		{Name: "archive", AWIPS: []string{"SEL*"}, Priority: 100, Handle: handle},
+		{Name: "first", Priority: 200, Handle: handle},
+	} {
+		if err := RegisterHandler(handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		awips string
+		want  string
+	}{
+		// The exclusive handler stops those of lower priority but not those of the same priority
+		{"SEL5", "first,watch,archive"},
+		{"WCNDMX", "first,wcn,vtec"},
+		{"TORDMX", "first,vtec"},
+	}
+	for _, test := range tests {
+		names := []string{}
+		for _, h := range HandlersFor(&Product{AWIPS: AWIPS{Original: test.awips}}) {
+			names = append(names, h.Name)
+		}
+		if got := strings.Join(names, ","); got != test.want {
+			t.Errorf("%s is handled by %s, want %s", test.awips, got, test.want)
+		}
+	}
+
+	if err := RegisterHandler(ProductHandler{Name: "vtec", Handle: handle}); err == nil {
+		t.Error("registering a name twice should fail")
+	}
+}
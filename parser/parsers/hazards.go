@@ -0,0 +1,238 @@
+package parsers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The catalog shipped with the parser, used until another is loaded
+//
+//go:embed hazards.json
+var defaultHazardCatalog []byte
+
+type HazardCode struct {
+	Code      string     `json:"code"`
+	Name      string     `json:"name"`
+	Effective *time.Time `json:"effective,omitempty"` // Codes introduced by hazard simplification aren't valid before this
+}
+
+// A VTEC phenomena and significance pair, e.g. TO.W
+type Hazard struct {
+	Phenomena    string     `json:"phenomena"`
+	Significance string     `json:"significance"`
+	Name         string     `json:"name"`
+	Color        string     `json:"color"`
+	Priority     int        `json:"priority"` // 1 is the most important
+	CAPEvent     string     `json:"cap_event"`
+	SAME         string     `json:"same,omitempty"`
+	Effective    *time.Time `json:"effective,omitempty"`
+}
+
+// The key the hazard is found under, e.g. TO.W
+func (h *Hazard) Key() string {
+	return h.Phenomena + "." + h.Significance
+}
+
+/*
+HazardMapping records a hazard that was renamed or consolidated into another under the NWS hazard
+simplification, e.g. Excessive Heat Warning (EH.W) becoming Extreme Heat Warning (XH.W).
+*/
+type HazardMapping struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Effective time.Time `json:"effective"`
+}
+
+/*
+HazardCatalog describes every phenomena and significance the NWS issues. It is loaded from a versioned JSON
+file so new hazards can be added without a code change. The file is also where the effective dates of new
+codes are set, so a date the NWS moves is changed by loading a catalog with the new date through HAZARD_CATALOG.
+*/
+type HazardCatalog struct {
+	Version      string          `json:"version"`
+	Phenomena    []HazardCode    `json:"phenomena"`
+	Significance []HazardCode    `json:"significance"`
+	Hazards      []Hazard        `json:"hazards"`
+	Mappings     []HazardMapping `json:"mappings"`
+	phenomena    map[string]*HazardCode
+	significance map[string]string
+	hazards      map[string]*Hazard
+	replacements map[string]*HazardMapping
+}
+
+// ParseHazardCatalog reads and checks a catalog
+func ParseHazardCatalog(data []byte) (*HazardCatalog, error) {
+	catalog := HazardCatalog{}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, err
+	}
+	if catalog.Version == "" {
+		return nil, errors.New("hazard catalog has no version")
+	}
+
+	catalog.phenomena = map[string]*HazardCode{}
+	for i := range catalog.Phenomena {
+		p := &catalog.Phenomena[i]
+		if len(p.Code) != 2 {
+			return nil, errors.New("invalid phenomena code " + p.Code + " in hazard catalog")
+		}
+		catalog.phenomena[p.Code] = p
+	}
+
+	catalog.significance = map[string]string{}
+	for _, s := range catalog.Significance {
+		if len(s.Code) != 1 {
+			return nil, errors.New("invalid significance code " + s.Code + " in hazard catalog")
+		}
+		catalog.significance[s.Code] = s.Name
+	}
+
+	sort.SliceStable(catalog.Hazards, func(i, j int) bool {
+		return catalog.Hazards[i].Priority < catalog.Hazards[j].Priority
+	})
+	catalog.hazards = map[string]*Hazard{}
+	for i := range catalog.Hazards {
+		hazard := &catalog.Hazards[i]
+		if _, ok := catalog.phenomena[hazard.Phenomena]; !ok {
+			return nil, fmt.Errorf("hazard %s has unknown phenomena", hazard.Key())
+		}
+		if _, ok := catalog.significance[hazard.Significance]; !ok {
+			return nil, fmt.Errorf("hazard %s has unknown significance", hazard.Key())
+		}
+		if _, ok := catalog.hazards[hazard.Key()]; ok {
+			return nil, fmt.Errorf("hazard %s is in the catalog twice", hazard.Key())
+		}
+		catalog.hazards[hazard.Key()] = hazard
+	}
+
+	catalog.replacements = map[string]*HazardMapping{}
+	for i := range catalog.Mappings {
+		mapping := &catalog.Mappings[i]
+		if _, ok := catalog.hazards[mapping.From]; !ok {
+			return nil, fmt.Errorf("mapping from unknown hazard %s", mapping.From)
+		}
+		if _, ok := catalog.hazards[mapping.To]; !ok {
+			return nil, fmt.Errorf("mapping to unknown hazard %s", mapping.To)
+		}
+		if _, ok := catalog.replacements[mapping.From]; ok {
+			return nil, fmt.Errorf("hazard %s is mapped twice", mapping.From)
+		}
+		catalog.replacements[mapping.From] = mapping
+	}
+	for from := range catalog.replacements {
+		seen := map[string]bool{}
+		for key := from; catalog.replacements[key] != nil; key = catalog.replacements[key].To {
+			if seen[key] {
+				return nil, fmt.Errorf("hazard mappings from %s loop", from)
+			}
+			seen[key] = true
+		}
+	}
+
+	return &catalog, nil
+}
+
+func LoadHazardCatalog(path string) (*HazardCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHazardCatalog(data)
+}
+
+func (c *HazardCatalog) ValidPhenomena(code string) bool {
+	_, ok := c.phenomena[code]
+	return ok
+}
+
+// ValidPhenomenaAt returns true if the phenomena had been introduced by t
+func (c *HazardCatalog) ValidPhenomenaAt(code string, t time.Time) bool {
+	p, ok := c.phenomena[code]
+	return ok && (p.Effective == nil || !t.Before(*p.Effective))
+}
+
+func (c *HazardCatalog) ValidSignificance(code string) bool {
+	_, ok := c.significance[code]
+	return ok
+}
+
+// Hazard returns the entry for the pair or nil if the catalog does not have it
+func (c *HazardCatalog) Hazard(phenomena string, significance string) *Hazard {
+	return c.hazards[phenomena+"."+significance]
+}
+
+// Name gives the official name of the hazard, falling back to the phenomena followed by the significance
+func (c *HazardCatalog) Name(phenomena string, significance string) string {
+	if hazard := c.Hazard(phenomena, significance); hazard != nil {
+		return hazard.Name
+	}
+	name := ""
+	if p, ok := c.phenomena[phenomena]; ok {
+		name = p.Name
+	}
+	return strings.TrimSpace(name + " " + c.significance[significance])
+}
+
+// ReplacedBy gives the mapping that retired the hazard or nil if it is still in use
+func (c *HazardCatalog) ReplacedBy(phenomena string, significance string) *HazardMapping {
+	return c.replacements[phenomena+"."+significance]
+}
+
+/*
+Current follows the mappings to the hazard that is issued today in place of the one given, e.g. HZ.W gives
+FZ.W. Products keep the code they were issued with, this is only for grouping old and new codes together.
+*/
+func (c *HazardCatalog) Current(phenomena string, significance string) string {
+	key := phenomena + "." + significance
+	for c.replacements[key] != nil {
+		key = c.replacements[key].To
+	}
+	return key
+}
+
+// Equivalent lists every hazard that Current groups with the one given, itself included
+func (c *HazardCatalog) Equivalent(phenomena string, significance string) []string {
+	current := c.Current(phenomena, significance)
+	keys := []string{current}
+	for from := range c.replacements {
+		if from != current && c.Current(from[:2], from[3:]) == current {
+			keys = append(keys, from)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var hazardCatalogLock = &sync.Mutex{}
+
+var hazardCatalog *HazardCatalog
+
+func SetHazardCatalog(catalog *HazardCatalog) {
+	hazardCatalogLock.Lock()
+	defer hazardCatalogLock.Unlock()
+
+	hazardCatalog = catalog
+}
+
+// GetHazardCatalog returns the loaded catalog, or the one shipped with the parser if none has been loaded
+func GetHazardCatalog() *HazardCatalog {
+	hazardCatalogLock.Lock()
+	defer hazardCatalogLock.Unlock()
+
+	if hazardCatalog == nil {
+		catalog, err := ParseHazardCatalog(defaultHazardCatalog)
+		if err != nil {
+			panic("invalid default hazard catalog: " + err.Error())
+		}
+		hazardCatalog = catalog
+	}
+
+	return hazardCatalog
+}
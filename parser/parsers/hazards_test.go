@@ -0,0 +1,91 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultHazardCatalog(t *testing.T) {
+	catalog, err := ParseHazardCatalog(defaultHazardCatalog)
+	if err != nil {
+		t.Fatalf("the embedded hazards.json does not load: %s", err.Error())
+	}
+	if catalog.Version == "" {
+		t.Error("the embedded catalog has no version")
+	}
+
+	for _, hazard := range catalog.Hazards {
+		if hazard.Name == "" || hazard.CAPEvent == "" || hazard.Priority < 1 {
+			t.Errorf("hazard %s is missing its name, CAP event or priority", hazard.Key())
+		}
+		if !strings.HasPrefix(hazard.Color, "#") {
+			t.Errorf("hazard %s has colour %q", hazard.Key(), hazard.Color)
+		}
+	}
+
+	names := map[string]string{
+		"TO.W": "Tornado Warning",
+		"SV.W": "Severe Thunderstorm Warning",
+		"WW.Y": "Winter Weather Advisory",
+	}
+	for key, want := range names {
+		if got := catalog.Name(key[:2], key[3:]); got != want {
+			t.Errorf("%s is named %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestHazardCatalogMappings(t *testing.T) {
+	catalog := GetHazardCatalog()
+
+	if current := catalog.Current("EH", "W"); current != "XH.W" {
+		t.Errorf("EH.W is now %s, want XH.W", current)
+	}
+	if current := catalog.Current("TO", "W"); current != "TO.W" {
+		t.Errorf("TO.W is now %s, want TO.W", current)
+	}
+	// Old and new codes are grouped whichever is asked for
+	for _, key := range []string{"FZ.W", "HZ.W"} {
+		if equivalent := strings.Join(catalog.Equivalent(key[:2], key[3:]), ","); equivalent != "FZ.W,HZ.W" {
+			t.Errorf("%s is grouped with %s, want FZ.W,HZ.W", key, equivalent)
+		}
+	}
+	// Old products keep the name they were issued with
+	if name := catalog.Name("EH", "W"); name != "Excessive Heat Warning" {
+		t.Errorf("EH.W is named %q", name)
+	}
+}
+
+func TestHazardCatalogEffective(t *testing.T) {
+	catalog := GetHazardCatalog()
+	hazard := catalog.Hazard("XH", "W")
+	if hazard == nil || hazard.Effective == nil {
+		t.Fatal("XH.W should only be valid from when it was introduced")
+	}
+
+	before := hazard.Effective.AddDate(0, 0, -1)
+	if _, err := ParsePVTEC("/O.NEW.KPSR.XH.W.0001.250303T1800Z-250304T0300Z/", before, UGC{}); err == nil {
+		t.Error("XH.W issued before it was introduced should be rejected")
+	}
+	if _, err := ParsePVTEC("/O.NEW.KPSR.XH.W.0001.250601T1800Z-250602T0300Z/", hazard.Effective.AddDate(0, 3, 0), UGC{}); err != nil {
+		t.Errorf("XH.W issued after it was introduced: %s", err.Error())
+	}
+}
+
+// The effective dates are set by the catalog that is loaded
+func TestHazardCatalogEffectiveFromFile(t *testing.T) {
+	data := strings.Replace(string(defaultHazardCatalog), `"effective": "2025-03-04T00:00:00Z"`, `"effective": "2025-01-01T00:00:00Z"`, -1)
+	catalog, err := ParseHazardCatalog([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := GetHazardCatalog()
+	SetHazardCatalog(catalog)
+	defer SetHazardCatalog(previous)
+
+	issued := testTime(t, "2025-02-01T18:00:00Z")
+	if _, err := ParsePVTEC("/O.NEW.KPSR.XH.W.0001.250201T1800Z-250202T0300Z/", issued, UGC{}); err != nil {
+		t.Errorf("XH.W should be accepted from the loaded date: %s", err.Error())
+	}
+}
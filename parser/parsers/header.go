@@ -0,0 +1,179 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The product families, from the first letter of the TTAAII
+const (
+	HeaderFamilyText     = "text"     // Forecasts, warnings, notices and analyses written to be read
+	HeaderFamilyBulletin = "bulletin" // Coded observations and climate data
+	HeaderFamilyOther    = "other"    // Gridded, binary, pictorial and XML data
+)
+
+var (
+	ErrNoHeader     = errors.New("product has no header")
+	ErrNoWMOHeading = errors.New("product has no WMO abbreviated heading")
+)
+
+// The data type designators (T1) of WMO Manual 386
+var wmoDataTypes = map[byte]string{
+	'A': "Analyses",
+	'B': "Addressed message",
+	'C': "Climatic data",
+	'D': "Grid point information (GRID)",
+	'E': "Satellite imagery",
+	'F': "Forecasts",
+	'G': "Grid point information (GRID)",
+	'H': "Grid point information (GRIB)",
+	'I': "Observational data (Binary coded)",
+	'J': "Forecast information (Binary coded)",
+	'K': "CREX",
+	'L': "Aviation information in XML",
+	'N': "Notices",
+	'O': "Oceanographic information (GRIB)",
+	'P': "Pictorial information (Binary coded)",
+	'Q': "Pictorial information regional (Binary coded)",
+	'S': "Surface data",
+	'T': "Satellite data",
+	'U': "Upper-air data",
+	'V': "National data",
+	'W': "Warnings",
+	'X': "Common Alert Protocol messages",
+	'Y': "GRIB regional use",
+}
+
+var wmoFamilies = map[byte]string{
+	'A': HeaderFamilyText,
+	'F': HeaderFamilyText,
+	'N': HeaderFamilyText,
+	'W': HeaderFamilyText,
+	'V': HeaderFamilyText,
+	'C': HeaderFamilyBulletin,
+	'S': HeaderFamilyBulletin,
+	'U': HeaderFamilyBulletin,
+}
+
+var (
+	sequenceRegexp   = regexp.MustCompile(`^[0-9]{3,5}$`)
+	wmoHeadingRegexp = regexp.MustCompile(`^([A-Z]{4}[0-9]{2}) ([A-Z0-9]{4}) ([0-9]{6})(?: ([A-Z]{3}))?$`)
+	afosRegexp       = regexp.MustCompile(`^([A-Z0-9]{3})([A-Z0-9]{1,3})$`)
+)
+
+/*
+Header is the leading line structure of a product as sent over NWWS-OI:
+
+	000
+	WFUS53 KTOP 142217
+	TORTOP
+
+The sequence number and the AWIPS identifier are optional, the WMO abbreviated heading is not.
+*/
+type Header struct {
+	Sequence string `json:"sequence,omitempty"`
+	TTAAII   string `json:"ttaaii"`
+	CCCC     string `json:"cccc"`
+	DDHHMM   string `json:"ddhhmm"`
+	BBB      string `json:"bbb,omitempty"`
+	AWIPS    *AWIPS `json:"awips,omitempty"`
+	Family   string `json:"family"`
+	DataType string `json:"data_type"`
+	Body     int    `json:"body"` // Byte offset of the first line after the header
+}
+
+type headerLine struct {
+	text string
+	end  int // Byte offset of the next line
+}
+
+// Keep the line endings and control characters of the transmission out of the header lines
+func cleanHeaderLine(line string) string {
+	return strings.TrimSpace(strings.Trim(line, "\x01\x03\r\n"))
+}
+
+// ParseHeader reads the header lines at the top of the product text
+func ParseHeader(text string) (*Header, error) {
+	header := Header{}
+
+	offset := 0
+	lines := []headerLine{}
+	for offset < len(text) && len(lines) < 3 {
+		end := strings.IndexByte(text[offset:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += offset + 1
+		}
+		line := cleanHeaderLine(text[offset:end])
+		offset = end
+		if line != "" {
+			lines = append(lines, headerLine{line, end})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoHeader
+	}
+
+	i := 0
+	if sequenceRegexp.MatchString(lines[i].text) {
+		header.Sequence = lines[i].text
+		i++
+	}
+
+	if i >= len(lines) {
+		return nil, ErrNoWMOHeading
+	}
+	match := wmoHeadingRegexp.FindStringSubmatch(lines[i].text)
+	if match == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNoWMOHeading, lines[i].text)
+	}
+	header.TTAAII = match[1]
+	header.CCCC = match[2]
+	header.DDHHMM = match[3]
+	header.BBB = match[4]
+	if _, err := time.Parse("021504", header.DDHHMM); err != nil {
+		return nil, fmt.Errorf("%w: invalid time %s", ErrNoWMOHeading, header.DDHHMM)
+	}
+	header.Family = wmoFamilies[header.TTAAII[0]]
+	if header.Family == "" {
+		header.Family = HeaderFamilyOther
+	}
+	header.DataType = wmoDataTypes[header.TTAAII[0]]
+	header.Body = lines[i].end
+	i++
+
+	if i < len(lines) {
+		if match := afosRegexp.FindStringSubmatch(lines[i].text); match != nil {
+			header.AWIPS = &AWIPS{
+				Original: match[0],
+				Product:  match[1],
+				WFO:      match[2],
+			}
+			header.Body = lines[i].end
+		}
+	}
+
+	return &header, nil
+}
+
+// WMO gives the WMO line with the day resolved to the month closest to the reference time
+func (h *Header) WMO(reference time.Time) WMO {
+	original := h.TTAAII + " " + h.CCCC + " " + h.DDHHMM
+	if h.BBB != "" {
+		original += " " + h.BBB
+	}
+
+	issuedDecoded, _ := time.Parse("021504", h.DDHHMM)
+
+	return WMO{
+		Original: original,
+		Datatype: h.TTAAII,
+		WFO:      h.CCCC,
+		Issued:   wmoTime(issuedDecoded.Day(), issuedDecoded.Hour(), issuedDecoded.Minute(), reference),
+		BBB:      h.BBB,
+	}
+}
@@ -0,0 +1,137 @@
+package parsers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		sequence string
+		ttaaii   string
+		bbb      string
+		awips    string // Empty when there is no AFOS line
+		family   string
+		body     string // The line the body starts at
+		err      error
+	}{
+		{
+			name:     "warning",
+			text:     "\x01\r\r\n000 \r\r\nWFUS53 KTOP 142217\r\r\nTORTOP\r\r\n\r\r\nBULLETIN - IMMEDIATE BROADCAST REQUESTED\n",
+			sequence: "000",
+			ttaaii:   "WFUS53",
+			awips:    "TORTOP",
+			family:   HeaderFamilyText,
+			body:     "\r\r\nBULLETIN",
+		},
+		{
+			name:   "no sequence",
+			text:   "FXUS63 KDMX 141130\nAFDDMX\n\nArea Forecast Discussion\n",
+			ttaaii: "FXUS63",
+			awips:  "AFDDMX",
+			family: HeaderFamilyText,
+			body:   "\nArea Forecast Discussion",
+		},
+		{
+			name:     "correction",
+			text:     "123\nWWUS83 KDMX 142217 CCA\nSPSDMX\n\nSpecial Weather Statement\n",
+			sequence: "123",
+			ttaaii:   "WWUS83",
+			bbb:      "CCA",
+			awips:    "SPSDMX",
+			family:   HeaderFamilyText,
+			body:     "\nSpecial Weather Statement",
+		},
+		{
+			name:   "short AFOS",
+			text:   "NOUS42 KWNO 141200\nADMN\n\nMessage\n",
+			ttaaii: "NOUS42",
+			awips:  "ADMN",
+			family: HeaderFamilyText,
+			body:   "\nMessage",
+		},
+		{
+			name:   "observations",
+			text:   "SAUS70 KWBC 141200\nMTRDSM\nMETAR KDSM 141154Z 18010KT 10SM CLR 22/12 A2992=\n",
+			ttaaii: "SAUS70",
+			awips:  "MTRDSM",
+			family: HeaderFamilyBulletin,
+			body:   "METAR KDSM",
+		},
+		{
+			name:   "missing AFOS",
+			text:   "CSUS43 KDMX 141200\nCLIMATE REPORT\n",
+			ttaaii: "CSUS43",
+			family: HeaderFamilyBulletin,
+			body:   "CLIMATE REPORT",
+		},
+		{
+			name:   "invalid AFOS",
+			text:   "YEUS41 KWBC 141200\nNOT-AFOS\n",
+			ttaaii: "YEUS41",
+			family: HeaderFamilyOther,
+			body:   "NOT-AFOS",
+		},
+		{
+			name:   "no AFOS line",
+			text:   "XOUS53 KWBC 141200",
+			ttaaii: "XOUS53",
+			family: HeaderFamilyOther,
+		},
+		{name: "empty", text: "\r\r\n\n", err: ErrNoHeader},
+		{name: "sequence only", text: "000\n", err: ErrNoWMOHeading},
+		{name: "no WMO heading", text: "000\nTORTOP\n", err: ErrNoWMOHeading},
+		{name: "invalid WMO time", text: "WFUS53 KTOP 322217\nTORTOP\n", err: ErrNoWMOHeading},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header, err := ParseHeader(test.text)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("got %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.Sequence != test.sequence || header.TTAAII != test.ttaaii || header.BBB != test.bbb || header.Family != test.family {
+				t.Errorf("got %s %s %s %s, want %s %s %s %s", header.Sequence, header.TTAAII, header.BBB, header.Family, test.sequence, test.ttaaii, test.bbb, test.family)
+			}
+			if header.DataType != wmoDataTypes[test.ttaaii[0]] {
+				t.Errorf("got data type %q", header.DataType)
+			}
+			awips := ""
+			if header.AWIPS != nil {
+				awips = header.AWIPS.Original
+				if header.AWIPS.Product+header.AWIPS.WFO != awips || len(header.AWIPS.Product) != 3 {
+					t.Errorf("got %+v", *header.AWIPS)
+				}
+			}
+			if awips != test.awips {
+				t.Errorf("got AFOS %q, want %q", awips, test.awips)
+			}
+			body := test.text[header.Body:]
+			if len(body) < len(test.body) || body[:len(test.body)] != test.body {
+				t.Errorf("got body %q, want it to start %q", body, test.body)
+			}
+		})
+	}
+}
+
+func TestHeaderWMO(t *testing.T) {
+	header, err := ParseHeader("WWUS83 KDMX 142217 CCA\nSPSDMX\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wmo := header.WMO(testTime(t, "2024-05-14T22:20:00Z"))
+	if wmo.Original != "WWUS83 KDMX 142217 CCA" || wmo.BBB != "CCA" || wmo.WFO != "KDMX" {
+		t.Errorf("got %+v", wmo)
+	}
+	if !wmo.Issued.Equal(testTime(t, "2024-05-14T22:17:00Z")) {
+		t.Errorf("got %s", wmo.Issued)
+	}
+}
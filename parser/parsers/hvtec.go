@@ -1,23 +1,171 @@
 package parsers
 
 import (
+	"errors"
 	"regexp"
+	"strings"
 	"time"
 )
 
+// Location ID used when the H-VTEC is not specific to a forecast point
+const HVTECNoLocation = "00000"
+
+var HVTECSeverities = map[string]string{
+	"N": "Not Applicable",
+	"0": "None",
+	"1": "Minor",
+	"2": "Moderate",
+	"3": "Major",
+	"U": "Unknown",
+}
+
+var HVTECCauses = map[string]string{
+	"ER": "Excessive Rainfall",
+	"SM": "Snowmelt",
+	"RS": "Rain and Snowmelt",
+	"DM": "Dam or Levee Failure",
+	"IJ": "Ice Jam",
+	"GO": "Glacier-Dammed Lake Outburst",
+	"IC": "Rain and/or Snowmelt and/or Ice Jam",
+	"FS": "Upstream Flooding plus Storm Surge",
+	"FT": "Upstream Flooding plus Tidal Effects",
+	"ET": "Elevated Upstream Flow plus Tidal Effects",
+	"WT": "Wind and/or Tidal Effects",
+	"DR": "Upstream Dam or Reservoir Release",
+	"MC": "Other Multiple Causes",
+	"OT": "Other Effects",
+	"UU": "Unknown",
+}
+
+var HVTECRecords = map[string]string{
+	"NO": "Record Flood Not Expected",
+	"NR": "Near Record or Record Flood Expected",
+	"UU": "Flood Without a Period of Record to Compare",
+	"OO": "Not Applicable",
+}
+
 type HVTEC struct {
-	Original string
+	Original string     `json:"original"`
+	NWSLI    string     `json:"nwsli"`
+	Severity string     `json:"severity"`
+	Cause    string     `json:"cause"`
+	Begin    *time.Time `json:"begin"`
+	Crest    *time.Time `json:"crest"`
+	End      *time.Time `json:"end"`
+	Record   string     `json:"record"`
+}
+
+// PointSpecific returns true if the H-VTEC refers to a forecast point rather than an area
+func (h *HVTEC) PointSpecific() bool {
+	return h.NWSLI != HVTECNoLocation
 }
 
-func ParseHVTEC(text string, issued time.Time) *HVTEC {
-	vtecRegexp := regexp.MustCompile(`([A-Z0-9]){5}\.([0-3UN])\.([A-Z]{2})(\.[0-9TZ]+){3}\.(OO|NO|NR|UU)`)
-	original := vtecRegexp.FindString(text)
+func (h *HVTEC) SeverityName() string {
+	return HVTECSeverities[h.Severity]
+}
 
-	if original == "" {
-		return nil
+// CauseName gives Unknown for causes added since the table was last updated, the code is kept as sent
+func (h *HVTEC) CauseName() string {
+	if name, ok := HVTECCauses[h.Cause]; ok {
+		return name
 	}
+	return HVTECCauses["UU"]
+}
+
+func (h *HVTEC) RecordName() string {
+	return HVTECRecords[h.Record]
+}
 
-	return &HVTEC{
-		Original: original,
+func parseHVTECTime(s string) (*time.Time, error) {
+	if s == "000000T0000Z" {
+		return nil, nil
+	}
+	t, err := time.Parse("060102T1504Z", s)
+	if err != nil {
+		return nil, err
 	}
+	return &t, nil
+}
+
+var hvtecRegexp = regexp.MustCompile(`([A-Z0-9]){5}\.([0-3UN])\.([A-Z]{2})(\.[0-9TZ]+){3}\.(OO|NO|NR|UU)`)
+
+// ParseHVTEC decodes every H-VTEC in the text in the order they appear
+func ParseHVTEC(text string) ([]HVTEC, error) {
+	hvtecs := []HVTEC{}
+
+	for _, original := range hvtecRegexp.FindAllString(text, -1) {
+		segments := strings.Split(original, ".")
+		if len(segments) != 7 {
+			return nil, errors.New("H-VTEC does not have 7 segments: " + original)
+		}
+
+		begin, err := parseHVTECTime(segments[3])
+		if err != nil {
+			return nil, errors.New("failed to parse H-VTEC begin time")
+		}
+		crest, err := parseHVTECTime(segments[4])
+		if err != nil {
+			return nil, errors.New("failed to parse H-VTEC crest time")
+		}
+		end, err := parseHVTECTime(segments[5])
+		if err != nil {
+			return nil, errors.New("failed to parse H-VTEC end time")
+		}
+
+		hvtecs = append(hvtecs, HVTEC{
+			Original: original,
+			NWSLI:    segments[0],
+			Severity: segments[1],
+			Cause:    segments[2],
+			Begin:    begin,
+			Crest:    crest,
+			End:      end,
+			Record:   segments[6],
+		})
+	}
+
+	return hvtecs, nil
+}
+
+/*
+pairHVTEC finds the H-VTEC for each P-VTEC in the text. The H-VTEC line follows the P-VTEC it belongs to,
+so each P-VTEC gets the first H-VTEC after it and before the next P-VTEC.
+*/
+func pairHVTEC(text string, vtecs []PVTEC, hvtecs []HVTEC) []*HVTEC {
+	paired := make([]*HVTEC, len(vtecs))
+	if len(hvtecs) == 0 {
+		return paired
+	}
+
+	positions := make([]int, len(vtecs))
+	from := 0
+	for i, vtec := range vtecs {
+		positions[i] = -1
+		if index := strings.Index(text[from:], vtec.Original); index >= 0 {
+			positions[i] = from + index
+			from = positions[i] + len(vtec.Original)
+		}
+	}
+
+	from = 0
+	for i := range hvtecs {
+		index := strings.Index(text[from:], hvtecs[i].Original)
+		if index < 0 {
+			continue
+		}
+		position := from + index
+		from = position + len(hvtecs[i].Original)
+
+		// The closest P-VTEC before it
+		for j := len(vtecs) - 1; j >= 0; j-- {
+			if positions[j] >= 0 && positions[j] < position {
+				if paired[j] == nil {
+					paired[j] = &hvtecs[i]
+				}
+				break
+			}
+		}
+	}
+
+	return paired
 }
@@ -0,0 +1,71 @@
+package parsers
+
+import (
+	"testing"
+)
+
+func TestParseHVTEC(t *testing.T) {
+	hvtecs, err := ParseHVTEC(`/O.NEW.KDMX.FL.W.0021.240612T1200Z-240615T0000Z/
+/AMEI4.2.ER.240612T1200Z.240613T1800Z.240614T1800Z.NO/
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hvtecs) != 1 {
+		t.Fatalf("got %d H-VTECs, want 1", len(hvtecs))
+	}
+
+	hvtec := hvtecs[0]
+	if hvtec.NWSLI != "AMEI4" || !hvtec.PointSpecific() {
+		t.Errorf("got forecast point %s", hvtec.NWSLI)
+	}
+	if hvtec.SeverityName() != "Moderate" || hvtec.CauseName() != "Excessive Rainfall" || hvtec.RecordName() != "Record Flood Not Expected" {
+		t.Errorf("got %s, %s, %s", hvtec.SeverityName(), hvtec.CauseName(), hvtec.RecordName())
+	}
+	if hvtec.Crest == nil || !hvtec.Crest.Equal(testTime(t, "2024-06-13T18:00:00Z")) {
+		t.Errorf("got crest %v", hvtec.Crest)
+	}
+}
+
+func TestParseHVTECUnknownCause(t *testing.T) {
+	hvtecs, err := ParseHVTEC("/00000.N.XX.000000T0000Z.000000T0000Z.000000T0000Z.OO/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hvtecs) != 1 {
+		t.Fatalf("got %d H-VTECs, want 1", len(hvtecs))
+	}
+	// The code the office sent is kept even though it has no name yet
+	if hvtecs[0].Cause != "XX" || hvtecs[0].CauseName() != "Unknown" {
+		t.Errorf("unknown cause decoded as %s", hvtecs[0].Cause)
+	}
+	if hvtecs[0].PointSpecific() {
+		t.Error("00000 is not a forecast point")
+	}
+	if hvtecs[0].Begin != nil || hvtecs[0].Crest != nil || hvtecs[0].End != nil {
+		t.Error("000000T0000Z should leave the times unset")
+	}
+}
+
+func TestVTECSegmentHVTECPairs(t *testing.T) {
+	segments := testVTECSegments(t, `IAC015-169-130000-
+/O.EXT.KDMX.FL.W.0021.000000T0000Z-240615T0600Z/
+/AMEI4.2.ER.240612T1200Z.240613T1800Z.240615T0600Z.NO/
+/O.NEW.KDMX.FL.A.0007.240613T1200Z-240616T0000Z/
+/BOOI4.1.ER.240613T1200Z.240614T1200Z.240616T0000Z.NO/
+Boone IA-Story IA-
+`, testTime(t, "2024-06-12T15:00:00Z"))
+
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	for i, want := range []string{"AMEI4", "BOOI4"} {
+		if segments[i].HVTEC == nil {
+			t.Errorf("%s has no H-VTEC", segments[i].VTEC.Original)
+			continue
+		}
+		if segments[i].HVTEC.NWSLI != want {
+			t.Errorf("%s paired with %s, want %s", segments[i].VTEC.Original, segments[i].HVTEC.NWSLI, want)
+		}
+	}
+}
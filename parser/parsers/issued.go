@@ -0,0 +1,213 @@
+package parsers
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+// Where the issued time of a product came from
+const (
+	IssuedSourceHeader = "header" // The issued line under the office name, e.g. 1012 PM CDT Tue May 14 2024
+	IssuedSourceWMO    = "wmo"    // The DDHHMM of the WMO line
+)
+
+// How far the issued line can be from the WMO line before the issued line is not trusted
+const IssuedTolerance = 30 * time.Minute
+
+// The time zones used on the issued line of the products. Abbreviations are upper case
+var timezones = map[string]*time.Location{
+	"GMT":  time.FixedZone("GMT", 0*60*60),
+	"UTC":  time.FixedZone("UTC", 0*60*60),
+	"AST":  time.FixedZone("AST", -4*60*60),
+	"ADT":  time.FixedZone("ADT", -3*60*60),
+	"EST":  time.FixedZone("EST", -5*60*60),
+	"EDT":  time.FixedZone("EDT", -4*60*60),
+	"CST":  time.FixedZone("CST", -6*60*60),
+	"CDT":  time.FixedZone("CDT", -5*60*60),
+	"MST":  time.FixedZone("MST", -7*60*60),
+	"MDT":  time.FixedZone("MDT", -6*60*60),
+	"PST":  time.FixedZone("PST", -8*60*60),
+	"PDT":  time.FixedZone("PDT", -7*60*60),
+	"AKST": time.FixedZone("AKST", -9*60*60),
+	"AKDT": time.FixedZone("AKDT", -8*60*60),
+	"HST":  time.FixedZone("HST", -10*60*60),
+	"HDT":  time.FixedZone("HDT", -9*60*60),
+	"SST":  time.FixedZone("SST", -11*60*60),
+	"CHST": time.FixedZone("ChST", 10*60*60),
+}
+
+// The zones of the offices outside of the lower 48 where the abbreviations are ambiguous or unusual
+var wfoTimezones = map[string]string{
+	"GUM": "Pacific/Guam",
+	"PPG": "Pacific/Pago_Pago",
+	"HFO": "Pacific/Honolulu",
+	"SJU": "America/Puerto_Rico",
+	"AFC": "America/Anchorage",
+	"AFG": "America/Anchorage",
+	"AJK": "America/Juneau",
+}
+
+// The locations loaded by name, as loading one reads the time zone database each time
+var (
+	locations     = map[string]*time.Location{}
+	locationsLock = &sync.Mutex{}
+)
+
+func loadLocation(name string) (*time.Location, error) {
+	locationsLock.Lock()
+	defer locationsLock.Unlock()
+
+	if loc, ok := locations[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations[name] = loc
+	return loc, nil
+}
+
+var (
+	issuedLocalRegexp = regexp.MustCompile(`(?m:^ *([0-9]{1,4}) (AM|PM) ([A-Za-z]{2,5}) [A-Za-z]{3} ([A-Za-z]{3}) +([0-9]{1,2}) ([0-9]{4}))`)
+	issuedUTCRegexp   = regexp.MustCompile(`(?m:^ *([0-9]{3,4}) ?(UTC|GMT|Z) [A-Za-z]{3} ([A-Za-z]{3}) +([0-9]{1,2}) ([0-9]{4}))`)
+)
+
+// The time zones the office issues in, from the UGC reference if it is loaded
+func officeTimezones(wfo string) []string {
+	wfo = strings.TrimSpace(wfo)
+	zones := []string{}
+	if ref := GetUGCReference(); ref != nil {
+		zones = ref.WFOTimeZones(wfo)
+	}
+	if tz, ok := wfoTimezones[wfo]; ok {
+		zones = append(zones, tz)
+	}
+	return zones
+}
+
+/*
+Find the location for the abbreviation on the issued line. The zones of the issuing office are tried first
+so that abbreviations such as SST (Samoa) or ChST (Guam) are read the way the office meant them.
+*/
+func issuedLocation(abbreviation string, wfo string, year int, month time.Month, day int, hour int, minute int) (time.Time, error) {
+	abbreviation = strings.ToUpper(abbreviation)
+
+	for _, name := range officeTimezones(wfo) {
+		loc, err := loadLocation(name)
+		if err != nil {
+			continue
+		}
+		t := time.Date(year, month, day, hour, minute, 0, 0, loc)
+		if zone, _ := t.Zone(); strings.ToUpper(zone) == abbreviation {
+			return t.UTC(), nil
+		}
+	}
+
+	loc, ok := timezones[abbreviation]
+	if !ok {
+		return time.Time{}, errors.New("missing timezone " + abbreviation)
+	}
+	return time.Date(year, month, day, hour, minute, 0, 0, loc).UTC(), nil
+}
+
+/*
+LocalTime reads a wall clock time in the zone given by the abbreviation, the way the issued line is read.
+Only the date and time of local are used, its location is ignored.
+*/
+func LocalTime(abbreviation string, wfo string, local time.Time) (time.Time, error) {
+	return issuedLocation(abbreviation, wfo, local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute())
+}
+
+func issuedDate(monthString string, dayString string, yearString string) (int, time.Month, int, error) {
+	var month time.Month
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(m.String()[:3], monthString) {
+			month = m
+		}
+	}
+	if month == 0 {
+		return 0, 0, 0, errors.New("invalid month " + monthString + " on issued line")
+	}
+	day, _ := strconv.Atoi(dayString)
+	year, _ := strconv.Atoi(yearString)
+	if day < 1 || day > 31 {
+		return 0, 0, 0, errors.New("invalid day " + dayString + " on issued line")
+	}
+	return year, month, day, nil
+}
+
+/*
+ParseIssuedLine reads the issued line under the office name. 12 AM is midnight and 12 PM is noon, the hour may
+be padded with a space and products from the national centres may be in UTC. Nil is returned when the product
+has no issued line.
+*/
+func ParseIssuedLine(text string, wfo string) (*time.Time, error) {
+	if match := issuedLocalRegexp.FindStringSubmatch(text); match != nil {
+		hhmm, _ := strconv.Atoi(match[1])
+		hour := hhmm / 100
+		minute := hhmm % 100
+		if len(match[1]) <= 2 {
+			hour = hhmm
+			minute = 0
+		}
+		if hour < 1 || hour > 12 || minute > 59 {
+			return nil, errors.New("invalid time " + match[1] + " on issued line")
+		}
+		hour = hour % 12
+		if match[2] == "PM" {
+			hour += 12
+		}
+
+		year, month, day, err := issuedDate(match[4], match[5], match[6])
+		if err != nil {
+			return nil, err
+		}
+
+		issued, err := issuedLocation(match[3], wfo, year, month, day, hour, minute)
+		if err != nil {
+			return nil, err
+		}
+		return &issued, nil
+	}
+
+	if match := issuedUTCRegexp.FindStringSubmatch(text); match != nil {
+		hhmm, _ := strconv.Atoi(match[1])
+		hour := hhmm / 100
+		minute := hhmm % 100
+		if hour > 23 || minute > 59 {
+			return nil, errors.New("invalid time " + match[1] + " on issued line")
+		}
+
+		year, month, day, err := issuedDate(match[3], match[4], match[5])
+		if err != nil {
+			return nil, err
+		}
+
+		issued := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+		return &issued, nil
+	}
+
+	return nil, nil
+}
+
+/*
+IssuedTime picks the issued time of a product. The issued line is used if it agrees with the WMO line, if it
+does not the zone on it was most likely wrong and the WMO line is used.
+*/
+func IssuedTime(header *time.Time, wmo WMO) (time.Time, string) {
+	if header == nil {
+		return wmo.Issued, IssuedSourceWMO
+	}
+
+	if header.Sub(wmo.Issued).Abs() > IssuedTolerance {
+		return wmo.Issued, IssuedSourceWMO
+	}
+
+	return *header, IssuedSourceHeader
+}
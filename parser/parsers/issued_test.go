@@ -0,0 +1,97 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIssuedLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		wfo  string
+		want string // Empty when there is no issued line
+		err  bool
+	}{
+		{"evening", "1012 PM CDT Tue May 14 2024", "DMX", "2024-05-15T03:12:00Z", false},
+		{"daylight time", "1012 AM EDT Tue May 14 2024", "BOX", "2024-05-14T14:12:00Z", false},
+		{"standard time", "1012 AM EST Tue Jan 14 2024", "BOX", "2024-01-14T15:12:00Z", false},
+		{"noon", "1200 PM EDT Tue May 14 2024", "BOX", "2024-05-14T16:00:00Z", false},
+		{"midnight", "1200 AM EDT Wed May 15 2024", "BOX", "2024-05-15T04:00:00Z", false},
+		{"space padded hour", " 912 AM CDT Tue May 14 2024", "DMX", "2024-05-14T14:12:00Z", false},
+		{"hour only", "9 AM CDT Tue May 14 2024", "DMX", "2024-05-14T14:00:00Z", false},
+		{"Guam", "1000 AM ChST Wed May 22 2024", "GUM", "2024-05-22T00:00:00Z", false},
+		{"American Samoa", "1000 AM SST Wed May 22 2024", "PPG", "2024-05-22T21:00:00Z", false},
+		// SST is Samoa, not a summer time, for any office
+		{"Samoa without the office", "1000 AM SST Wed May 22 2024", "", "2024-05-22T21:00:00Z", false},
+		{"Hawaii", "1000 AM HST Wed May 22 2024", "HFO", "2024-05-22T20:00:00Z", false},
+		{"Alaska", "1000 AM AKDT Wed May 22 2024", "AFC", "2024-05-22T18:00:00Z", false},
+		{"UTC", "1630 UTC Tue May 14 2024", "", "2024-05-14T16:30:00Z", false},
+		{"Z", "1630Z Tue May 14 2024", "", "2024-05-14T16:30:00Z", false},
+		{"unknown zone", "1012 PM XYZ Tue May 14 2024", "DMX", "", true},
+		{"13 PM", "1312 PM CDT Tue May 14 2024", "DMX", "", true},
+		{"60 minutes", "1060 PM CDT Tue May 14 2024", "DMX", "", true},
+		{"bad month", "1012 PM CDT Tue Mey 14 2024", "DMX", "", true},
+		{"bad day", "1012 PM CDT Tue May 32 2024", "DMX", "", true},
+		{"bad UTC hour", "2430 UTC Tue May 14 2024", "", "", true},
+		{"no issued line", "Nothing to see here", "DMX", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text := "000\nWFUS53 KDMX 150312\nTORDMX\n\nNational Weather Service\n" + test.line + "\n"
+			issued, err := ParseIssuedLine(text, test.wfo)
+			if test.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", issued)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == "" {
+				if issued != nil {
+					t.Errorf("got %s, want no issued line", issued)
+				}
+				return
+			}
+			if issued == nil {
+				t.Fatal("found no issued line")
+			}
+			if !issued.Equal(testTime(t, test.want)) {
+				t.Errorf("got %s, want %s", issued.UTC().Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestIssuedTime(t *testing.T) {
+	wmo := WMO{Issued: testTime(t, "2024-05-15T03:12:00Z")}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		source string
+	}{
+		{"no issued line", "", "2024-05-15T03:12:00Z", IssuedSourceWMO},
+		{"agrees", "2024-05-15T03:13:00Z", "2024-05-15T03:13:00Z", IssuedSourceHeader},
+		{"at the tolerance", "2024-05-15T02:42:00Z", "2024-05-15T02:42:00Z", IssuedSourceHeader},
+		// An hour out is the zone on the issued line being wrong
+		{"an hour out", "2024-05-15T04:12:00Z", "2024-05-15T03:12:00Z", IssuedSourceWMO},
+		{"past the tolerance", "2024-05-15T03:43:00Z", "2024-05-15T03:12:00Z", IssuedSourceWMO},
+	}
+
+	for _, test := range tests {
+		var header *time.Time
+		if test.header != "" {
+			h := testTime(t, test.header)
+			header = &h
+		}
+		issued, source := IssuedTime(header, wmo)
+		if !issued.Equal(testTime(t, test.want)) || source != test.source {
+			t.Errorf("%s: got %s from %s, want %s from %s", test.name, issued.Format(time.RFC3339), source, test.want, test.source)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package parsers
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type LATLON struct {
@@ -22,77 +24,128 @@ type MultiPolygonFeature struct {
 	Coordinates [][][][2]float64 `json:"coordinates"`
 }
 
+// LAT...LON lines continue over lines that start with spaces and hold nothing but numbers
+var latlonRegexp = regexp.MustCompile(`(?m:^LAT\.\.\.LON((?:[ \t]+[0-9]+)+[ \t\r]*(?:\n[ \t]+[0-9]+(?:[ \t]+[0-9]+)*[ \t\r]*)*))`)
+
+/*
+Decode a longitude given in hundredths of a degree west. Offices in the Pacific carry on past 180 so Guam at
+144.8E is written as 21520 and is wrapped back round to the eastern hemisphere.
+*/
+func westLongitude(hundredths int) float64 {
+	lon := (float64(hundredths) / 100) * -1
+	if lon <= -180.0 {
+		lon = lon + 360.0
+	}
+	return lon
+}
+
+/*
+ParsePoint decodes a point either from a latitude and longitude pair, e.g. 3900 9580, or from the eight digit
+form the SPC uses, e.g. 39009580. In the eight digit form the leading 1 of longitudes past 100W is dropped.
+*/
 func ParsePoint(segments []string) (*[2]float64, error) {
-	if len(segments[0]) > 5 {
+	switch len(segments) {
+	case 1:
 		s := segments[0]
+		if len(s) != 8 {
+			return nil, fmt.Errorf("point %q is not 8 digits", s)
+		}
 		latInit, err := strconv.Atoi(s[0:4])
 		if err != nil {
-			return nil, errors.New("failed to parse point latitude")
+			return nil, fmt.Errorf("failed to parse point latitude %q", s[0:4])
 		}
-
 		lonInit, err := strconv.Atoi(s[4:8])
 		if err != nil {
-			return nil, errors.New("failed to parse point longitude")
+			return nil, fmt.Errorf("failed to parse point longitude %q", s[4:8])
 		}
 
-		lat := (float64(latInit) / 100)
-		lon := (float64(lonInit) / 100) * -1
-		if lon > -20.0 {
-			lon = lon + -100
+		lat := float64(latInit) / 100
+		lon := float64(lonInit) / 100
+		if lon < 40.0 {
+			lon += 100
 		}
 
-		return &[2]float64{lon, lat}, nil
-	} else {
-		if len(segments) > 2 {
-			return nil, errors.New("point string was not the correct size")
+		return &[2]float64{lon * -1, lat}, nil
+	case 2:
+		if len(segments[0]) < 3 || len(segments[0]) > 4 {
+			return nil, fmt.Errorf("point latitude %q is not 3 or 4 digits", segments[0])
+		}
+		if len(segments[1]) < 3 || len(segments[1]) > 5 {
+			return nil, fmt.Errorf("point longitude %q is not 3 to 5 digits", segments[1])
 		}
 		latInit, err := strconv.Atoi(segments[0])
 		if err != nil {
-			return nil, errors.New("failed to parse point latitude")
+			return nil, fmt.Errorf("failed to parse point latitude %q", segments[0])
 		}
 		lonInit, err := strconv.Atoi(segments[1])
 		if err != nil {
-			return nil, errors.New("failed to parse point longitude")
+			return nil, fmt.Errorf("failed to parse point longitude %q", segments[1])
 		}
 
-		lat := (float64(latInit) / 100)
-		lon := (float64(lonInit) / 100) * -1
-		if lon <= -180.0 {
-			lon = lon + 360.0
-		}
-		return &[2]float64{lon, lat}, nil
+		return &[2]float64{westLongitude(lonInit), float64(latInit) / 100}, nil
 	}
+	return nil, fmt.Errorf("point has %d values, not 1 or 2", len(segments))
 }
 
-func ParseLatLon(text string) (*LATLON, error) {
-	latlonRegexp := regexp.MustCompile(`(?m:^LAT\.\.\.LON\s+(\d+\s+)+)`)
-	original := latlonRegexp.FindString(text)
-
-	if original == "" {
-		return nil, nil
+// Keep each longitude within 180 degrees of the one before so shapes that cross the date line stay whole
+func unwrapLongitudes(points [][2]float64) {
+	for i := 1; i < len(points); i++ {
+		for points[i][0]-points[i-1][0] > 180 {
+			points[i][0] -= 360
+		}
+		for points[i][0]-points[i-1][0] < -180 {
+			points[i][0] += 360
+		}
 	}
+}
 
-	segmentRegexp := regexp.MustCompile("[0-9]{4,}")
-	segments := segmentRegexp.FindAllString(original, -1)
-
+// Decode a list of values as points, either all in pairs or all in the eight digit form
+func parsePoints(values []string) ([][2]float64, error) {
 	points := [][2]float64{}
-	if len(segments[0]) > 5 {
-		for _, s := range segments {
-			point, err := ParsePoint([]string{s})
+	if len(values) == 0 {
+		return points, nil
+	}
+
+	if len(values[0]) == 8 {
+		for _, v := range values {
+			point, err := ParsePoint([]string{v})
 			if err != nil {
 				return nil, err
 			}
 			points = append(points, *point)
 		}
 	} else {
-		for i := 0; i < len(segments); i += 2 {
-			point, err := ParsePoint([]string{segments[i], segments[i+1]})
+		if len(values)%2 != 0 {
+			return nil, fmt.Errorf("odd number of values (%d), the last point has no longitude", len(values))
+		}
+		for i := 0; i < len(values); i += 2 {
+			point, err := ParsePoint([]string{values[i], values[i+1]})
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("point %d: %s", i/2+1, err.Error())
 			}
 			points = append(points, *point)
 		}
 	}
+
+	unwrapLongitudes(points)
+	return points, nil
+}
+
+// ParseLatLon reads the LAT...LON polygon, including the lines it wraps on to
+func ParseLatLon(text string) (*LATLON, error) {
+	match := latlonRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return nil, nil
+	}
+	original := strings.TrimRight(match[0], " \t\r\n")
+
+	points, err := parsePoints(strings.Fields(match[1]))
+	if err != nil {
+		return nil, errors.New("LAT...LON: " + err.Error())
+	}
+	if len(points) < 3 {
+		return nil, fmt.Errorf("LAT...LON: %d points is not enough for a polygon", len(points))
+	}
 	if points[0] != points[len(points)-1] {
 		points = append(points, points[0])
 	}
@@ -109,3 +162,13 @@ func ParseLatLon(text string) (*LATLON, error) {
 	}, nil
 
 }
+
+type PointFeature struct {
+	Type        string     `json:"type"` // Point
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type LineStringFeature struct {
+	Type        string       `json:"type"` // LineString
+	Coordinates [][2]float64 `json:"coordinates"`
+}
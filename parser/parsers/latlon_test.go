@@ -0,0 +1,164 @@
+package parsers
+
+import (
+	"math"
+	"testing"
+)
+
+func closePoints(a [][2]float64, b [][2]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i][0]-b[i][0]) > 1e-9 || math.Abs(a[i][1]-b[i][1]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseLatLon(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		original string
+		points   [][2]float64 // Nil when there is no polygon
+		err      bool
+	}{
+		{
+			name:     "one line",
+			text:     "LAT...LON 4198 9370 4209 9352 4192 9341\nTIME...MOT...LOC 2232Z 246DEG 29KT 4199 9357\n",
+			original: "LAT...LON 4198 9370 4209 9352 4192 9341",
+			points:   [][2]float64{{-93.7, 41.98}, {-93.52, 42.09}, {-93.41, 41.92}, {-93.7, 41.98}},
+		},
+		{
+			name:     "wrapped",
+			text:     "LAT...LON 4237 9344 4239 9313 4248 9308 4250 9350\r\n      4244 9374\r\n      4240 10102\nTIME...MOT...LOC 2232Z 246DEG 30KT 4231 9363\n",
+			original: "LAT...LON 4237 9344 4239 9313 4248 9308 4250 9350\r\n      4244 9374\r\n      4240 10102",
+			points:   [][2]float64{{-93.44, 42.37}, {-93.13, 42.39}, {-93.08, 42.48}, {-93.5, 42.5}, {-93.74, 42.44}, {-101.02, 42.4}, {-93.44, 42.37}},
+		},
+		{
+			name:     "already closed",
+			text:     "LAT...LON 4198 9370 4209 9352 4192 9341 4198 9370\n\n$$\n",
+			original: "LAT...LON 4198 9370 4209 9352 4192 9341 4198 9370",
+			points:   [][2]float64{{-93.7, 41.98}, {-93.52, 42.09}, {-93.41, 41.92}, {-93.7, 41.98}},
+		},
+		{
+			name:     "Guam",
+			text:     "LAT...LON 1340 21520 1360 21480 1320 21470\n",
+			original: "LAT...LON 1340 21520 1360 21480 1320 21470",
+			points:   [][2]float64{{144.8, 13.4}, {145.2, 13.6}, {145.3, 13.2}, {144.8, 13.4}},
+		},
+		{
+			// The longitudes either side of 180 are kept next to each other, not a world apart
+			name:     "date line",
+			text:     "LAT...LON 5200 17990 5210 18010 5190 18020\n",
+			original: "LAT...LON 5200 17990 5210 18010 5190 18020",
+			points:   [][2]float64{{-179.9, 52}, {-180.1, 52.1}, {-180.2, 51.9}, {-179.9, 52}},
+		},
+		{
+			name:     "not wrapped on to the next paragraph",
+			text:     "LAT...LON 4198 9370 4209 9352 4192 9341\n4209 9352\n",
+			original: "LAT...LON 4198 9370 4209 9352 4192 9341",
+			points:   [][2]float64{{-93.7, 41.98}, {-93.52, 42.09}, {-93.41, 41.92}, {-93.7, 41.98}},
+		},
+		{name: "none", text: "TIME...MOT...LOC 2232Z 246DEG 29KT 4199 9357\n"},
+		{name: "odd values", text: "LAT...LON 4198 9370 4209 9352 4192\n", err: true},
+		{name: "two points", text: "LAT...LON 4198 9370 4209 9352\n", err: true},
+		{name: "long latitude", text: "LAT...LON 41980 9370 4209 9352 4192 9341\n", err: true},
+		{name: "short longitude", text: "LAT...LON 4198 93 4209 9352 4192 9341\n", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			latlon, err := ParseLatLon(test.text)
+			if test.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", latlon)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.points == nil {
+				if latlon != nil {
+					t.Errorf("got %v, want no polygon", latlon)
+				}
+				return
+			}
+			if latlon == nil {
+				t.Fatal("found no polygon")
+			}
+			if latlon.Original != test.original {
+				t.Errorf("got original %q, want %q", latlon.Original, test.original)
+			}
+			if !closePoints(latlon.Points, test.points) {
+				t.Errorf("got %v, want %v", latlon.Points, test.points)
+			}
+			if latlon.Polygon == nil || latlon.Polygon.Type != "Polygon" || !closePoints(latlon.Polygon.Coordinates[0], test.points) {
+				t.Errorf("got polygon %v", latlon.Polygon)
+			}
+		})
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   [2]float64
+		err    bool
+	}{
+		{[]string{"4198", "9370"}, [2]float64{-93.7, 41.98}, false},
+		{[]string{"1340", "21520"}, [2]float64{144.8, 13.4}, false},
+		{[]string{"1930", "15510"}, [2]float64{-155.1, 19.3}, false},
+		{[]string{"39009580"}, [2]float64{-95.8, 39}, false},
+		// The leading 1 is dropped for longitudes past 100W
+		{[]string{"45000150"}, [2]float64{-101.5, 45}, false},
+		{[]string{"3900958"}, [2]float64{}, true},
+		{[]string{"39", "9580"}, [2]float64{}, true},
+		{[]string{"3900", "958000"}, [2]float64{}, true},
+		{[]string{"39OO", "9580"}, [2]float64{}, true},
+		{[]string{"3900", "9580", "4000"}, [2]float64{}, true},
+		{[]string{}, [2]float64{}, true},
+	}
+
+	for _, test := range tests {
+		point, err := ParsePoint(test.values)
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: got %v, want an error", test.values, *point)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %s", test.values, err)
+			continue
+		}
+		if !closePoints([][2]float64{*point}, [][2]float64{test.want}) {
+			t.Errorf("%v: got %v, want %v", test.values, *point, test.want)
+		}
+	}
+}
+
+func TestUnwrapLongitudes(t *testing.T) {
+	tests := []struct {
+		name   string
+		points [][2]float64
+		want   [][2]float64
+	}{
+		{"empty", [][2]float64{}, [][2]float64{}},
+		{"one point", [][2]float64{{179.9, 52}}, [][2]float64{{179.9, 52}}},
+		{"no wrap", [][2]float64{{-93.7, 41.98}, {-93.52, 42.09}}, [][2]float64{{-93.7, 41.98}, {-93.52, 42.09}}},
+		{"west to east", [][2]float64{{-179.9, 52}, {179.9, 52.1}, {179.8, 51.9}}, [][2]float64{{-179.9, 52}, {-180.1, 52.1}, {-180.2, 51.9}}},
+		{"east to west", [][2]float64{{179.9, 52}, {-179.9, 52.1}}, [][2]float64{{179.9, 52}, {180.1, 52.1}}},
+		{"back again", [][2]float64{{179.9, 52}, {-179.9, 52.1}, {179.8, 51.9}}, [][2]float64{{179.9, 52}, {180.1, 52.1}, {179.8, 51.9}}},
+	}
+
+	for _, test := range tests {
+		unwrapLongitudes(test.points)
+		if !closePoints(test.points, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, test.points, test.want)
+		}
+	}
+}
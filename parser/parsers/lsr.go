@@ -0,0 +1,245 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/util"
+)
+
+// The kinds of storm report
+const (
+	LSRTornado = "tornado"
+	LSRFunnel  = "funnel_cloud"
+	LSRHail    = "hail"
+	LSRWind    = "wind"
+	LSRFlood   = "flood"
+	LSRRain    = "rain"
+	LSRSnow    = "snow"
+	LSRIce     = "ice"
+	LSROther   = "other"
+)
+
+// Words in the event that give the kind of report, checked in order
+var lsrTypes = []struct {
+	Match string
+	Type  string
+}{
+	{"TORNADO", LSRTornado},
+	{"FUNNEL", LSRFunnel},
+	{"WATER SPOUT", LSRFunnel},
+	{"WATERSPOUT", LSRFunnel},
+	{"HAIL", LSRHail},
+	{"FREEZING", LSRIce},
+	{"SLEET", LSRIce},
+	{"ICE", LSRIce},
+	{"SNOW", LSRSnow},
+	{"BLIZZARD", LSRSnow},
+	{"FLOOD", LSRFlood},
+	{"RAIN", LSRRain},
+	{"WND", LSRWind},
+	{"WIND", LSRWind},
+	{"DOWNBURST", LSRWind},
+}
+
+type LSR struct {
+	ID        string        `json:"id"`
+	Original  string        `json:"original"`
+	WFO       string        `json:"wfo"`
+	Issued    time.Time     `json:"issued"`
+	Time      time.Time     `json:"time"`
+	Event     string        `json:"event"`
+	Type      string        `json:"type"`
+	Magnitude *float64      `json:"magnitude,omitempty"`
+	Qualifier string        `json:"qualifier,omitempty"` // E (estimated), M (measured) or U (unknown)
+	Units     string        `json:"units,omitempty"`
+	Location  string        `json:"location"`
+	County    string        `json:"county"`
+	State     string        `json:"state"`
+	Source    string        `json:"source"`
+	Remarks   string        `json:"remarks"`
+	Point     *PointFeature `json:"point"`
+	Corrected bool          `json:"corrected"`
+	Summary   bool          `json:"summary"`
+}
+
+// Get the text of a fixed width column, allowing for lines that have been cut short
+func lsrColumn(line string, start int, end int) string {
+	if start >= len(line) {
+		return ""
+	}
+	if end > len(line) || end < 0 {
+		end = len(line)
+	}
+	return strings.TrimSpace(line[start:end])
+}
+
+// Offices write the event in upper or mixed case
+func lsrType(event string) string {
+	event = strings.ToUpper(event)
+	for _, t := range lsrTypes {
+		if strings.Contains(event, t.Match) {
+			return t.Type
+		}
+	}
+	return LSROther
+}
+
+/*
+ParseLSR parses the reports in a Local Storm Report. Both the single report and the summary forms
+lay each report out the same way:
+
+	0225 PM     HAIL             2 N NAPERVILLE          41.80N 88.15W
+	05/02/2023  M1.00 INCH       DUPAGE             IL   TRAINED SPOTTER
+
+	            QUARTER SIZED HAIL.
+*/
+func ParseLSR(product *Product) ([]LSR, error) {
+	segments := product.Segments()
+	text := strings.ReplaceAll(segments[0].Text, "\r", "")
+
+	// The reports are in the zone of the issued line
+	abbreviation := "UTC"
+	tzRegexp := regexp.MustCompile(`[0-9]{3,4} (?:AM|PM) ([A-Za-z]{3,4}) `)
+	if match := tzRegexp.FindStringSubmatch(text); match != nil {
+		abbreviation = match[1]
+	}
+
+	timeRegexp := regexp.MustCompile(`^([0-9]{4} [AP]M|[0-9]{4} UTC)\s`)
+
+	// Only look at the heading so remarks don't set the flags
+	header := text
+	if first := regexp.MustCompile(`(?m:^[0-9]{4} (?:[AP]M|UTC)\s)`).FindStringIndex(text); first != nil {
+		header = text[:first[0]]
+	}
+	corrected := strings.HasPrefix(product.WMO.BBB, "CC") || strings.Contains(header, "CORRECTED")
+	summary := strings.Contains(header, "SUMMARY")
+
+	dateRegexp := regexp.MustCompile(`^[0-9]{2}/[0-9]{2}/[0-9]{4}`)
+	latlonRegexp := regexp.MustCompile(`([0-9.]+)([NS])\s+([0-9.]+)([EW])\s*$`)
+	magnitudeRegexp := regexp.MustCompile(`^([EMU])?([0-9]*\.?[0-9]+)\s*(.*)$`)
+
+	// The reports are in the parts of each segment closed by &&, or the whole segment when it has none
+	blocks := []string{}
+	for _, segment := range segments {
+		if len(segment.Sections) == 0 {
+			blocks = append(blocks, segment.Text)
+		}
+		for _, section := range segment.Sections {
+			blocks = append(blocks, section.Text)
+		}
+	}
+
+	reports := []LSR{}
+	for _, block := range blocks {
+		lines := strings.Split(strings.ReplaceAll(block, "\r", ""), "\n")
+		for i := 0; i < len(lines)-1; i++ {
+			first := strings.TrimRight(lines[i], " ")
+			second := strings.TrimRight(lines[i+1], " ")
+			if !timeRegexp.MatchString(first) || !dateRegexp.MatchString(second) {
+				continue
+			}
+
+			report := LSR{
+				ID:        product.ID + util.PadZero(strconv.Itoa(len(reports)), 3),
+				WFO:       product.AWIPS.WFO,
+				Issued:    product.Issued,
+				Corrected: corrected,
+				Summary:   summary,
+			}
+
+			// The first line has the time, event, location and position
+			eventLine := first
+			if match := latlonRegexp.FindStringSubmatchIndex(first); match != nil {
+				lat, err := strconv.ParseFloat(first[match[2]:match[3]], 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse LSR latitude on %s", first)
+				}
+				lon, err := strconv.ParseFloat(first[match[6]:match[7]], 64)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse LSR longitude on %s", first)
+				}
+				if first[match[4]:match[5]] == "S" {
+					lat = -lat
+				}
+				if first[match[8]:match[9]] == "W" {
+					lon = -lon
+				}
+				report.Point = &PointFeature{
+					Type:        "Point",
+					Coordinates: [2]float64{lon, lat},
+				}
+				eventLine = first[:match[0]]
+			}
+			report.Event = lsrColumn(eventLine, 12, 29)
+			report.Type = lsrType(report.Event)
+			report.Location = lsrColumn(eventLine, 29, -1)
+
+			// The second has the date, magnitude, county, state and source
+			report.County = lsrColumn(second, 29, 48)
+			report.State = lsrColumn(second, 48, 53)
+			report.Source = lsrColumn(second, 53, -1)
+
+			if magnitude := lsrColumn(second, 12, 29); magnitude != "" {
+				if match := magnitudeRegexp.FindStringSubmatch(magnitude); match != nil {
+					value, err := strconv.ParseFloat(match[2], 64)
+					if err == nil {
+						report.Magnitude = &value
+					}
+					report.Qualifier = match[1]
+					report.Units = match[3]
+				} else {
+					report.Units = magnitude
+				}
+			}
+
+			var err error
+			timeString := lsrColumn(first, 0, 12) + " " + lsrColumn(second, 0, 12)
+			if strings.HasSuffix(lsrColumn(first, 0, 12), "UTC") {
+				report.Time, err = time.ParseInLocation("1504 UTC 01/02/2006", timeString, time.UTC)
+			} else {
+				report.Time, err = time.Parse("0304 PM 01/02/2006", timeString)
+				if err == nil {
+					report.Time, err = LocalTime(abbreviation, product.AWIPS.WFO, report.Time)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not parse LSR time %s: %s", timeString, err.Error())
+			}
+			report.Time = report.Time.UTC()
+
+			// The remarks are the indented lines that follow, up to the next report
+			original := []string{first, second}
+			remarks := []string{}
+			j := i + 2
+			for ; j < len(lines); j++ {
+				line := strings.TrimRight(lines[j], " ")
+				if timeRegexp.MatchString(line) || strings.HasPrefix(line, "&&") || strings.HasPrefix(line, "$$") {
+					break
+				}
+				if line != "" && line[0] != ' ' {
+					break
+				}
+				original = append(original, line)
+				if trimmed := strings.TrimSpace(line); trimmed != "" {
+					remarks = append(remarks, trimmed)
+				}
+			}
+			report.Remarks = strings.Join(remarks, " ")
+			report.Original = strings.TrimSpace(strings.Join(original, "\n"))
+
+			reports = append(reports, report)
+			i = j - 1
+		}
+	}
+
+	if len(reports) == 0 {
+		return nil, errors.New("found no reports in LSR " + product.AWIPS.Original)
+	}
+
+	return reports, nil
+}
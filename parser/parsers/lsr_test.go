@@ -0,0 +1,78 @@
+package parsers
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func testLSR(t *testing.T, name string) []LSR {
+	t.Helper()
+	text, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := NewAWIPSProduct(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := product.LSRProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reports
+}
+
+func TestParseLSR(t *testing.T) {
+	reports := testLSR(t, "LSRDMX.txt")
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+
+	tornado := reports[0]
+	if tornado.Type != LSRTornado || tornado.County != "Boone" || tornado.Location != "2 N Boone" || tornado.Remarks != "Brief tornado touchdown in an open field." {
+		t.Errorf("got %+v", tornado)
+	}
+	if !tornado.Time.Equal(testTime(t, "2024-05-21T21:15:00Z")) {
+		t.Errorf("got time %s, want 21:15 UTC", tornado.Time)
+	}
+
+	hail := reports[1]
+	if hail.Type != LSRHail || hail.Magnitude == nil || *hail.Magnitude != 1.75 || hail.Qualifier != "E" {
+		t.Errorf("got %+v", hail)
+	}
+}
+
+// Guam gives its zone as ChST, which has to be matched whatever the case
+func TestParseLSRChamorroTime(t *testing.T) {
+	reports := testLSR(t, "LSRGUM.txt")
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reports))
+	}
+	if !reports[0].Time.Equal(testTime(t, "2024-06-15T04:15:00Z")) {
+		t.Errorf("got time %s, want 04:15 UTC", reports[0].Time)
+	}
+	if reports[0].Point == nil || reports[0].Point.Coordinates[0] != 144.79 {
+		t.Errorf("got point %+v", reports[0].Point)
+	}
+}
+
+func TestParseLSRWithoutSections(t *testing.T) {
+	text, err := os.ReadFile("testdata/LSRDMX.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an && the reports are read from the whole segment
+	product, err := NewAWIPSProduct(strings.Replace(string(text), "&&", "", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := product.LSRProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+}
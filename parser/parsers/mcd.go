@@ -10,45 +10,198 @@ import (
 	"github.com/TheRangiCrew/NWWS-GO/parser/util"
 )
 
+// What an MCD is concerning
+const (
+	MCDSeverePotential = "severe_potential"
+	MCDTornadoWatch    = "tornado_watch"
+	MCDSevereWatch     = "severe_thunderstorm_watch"
+	MCDHeavySnow       = "heavy_snow"
+	MCDWinterMixed     = "winter_mixed_precipitation"
+	MCDFreezingRain    = "freezing_rain"
+	MCDBlizzard        = "blizzard"
+	MCDSnowSquall      = "snow_squall"
+	MCDHeavyRainfall   = "heavy_rainfall"
+	MCDFireWeather     = "fire_weather"
+	MCDOther           = "other"
+)
+
+// The start of the concerning line for each type, checked in order
+var mcdConcerningTypes = []struct {
+	Prefix string
+	Type   string
+}{
+	{"severe potential", MCDSeverePotential},
+	{"tornado watch", MCDTornadoWatch},
+	{"severe thunderstorm watch", MCDSevereWatch},
+	{"heavy snow", MCDHeavySnow},
+	{"winter mixed", MCDWinterMixed},
+	{"freezing rain", MCDFreezingRain},
+	{"blizzard", MCDBlizzard},
+	{"snow squall", MCDSnowSquall},
+	{"heavy rain", MCDHeavyRainfall},
+	{"fire weather", MCDFireWeather},
+}
+
+type MCDWatch struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"` // TO or SV
+	Number int    `json:"number"`
+}
+
+/*
+MCD is a mesoscale discussion. It holds both the SPC's MCDs and the WPC's MPDs which share the same layout.
+*/
 type MCD struct {
 	ID               string          `json:"id"`
+	Type             string          `json:"type"` // MCD or MPD
 	Original         string          `json:"original"`
 	Number           int             `json:"number"`
 	Issued           time.Time       `json:"issued"`
 	Expires          time.Time       `json:"expires"`
 	Polygon          *PolygonFeature `json:"polygon"`
 	WatchProbability int             `json:"watch_probability"`
+	AreasAffected    string          `json:"areas_affected"`
 	Concerning       string          `json:"concerning"`
+	ConcerningType   string          `json:"concerning_type"`
+	Likelihood       string          `json:"likelihood,omitempty"` // Of a watch for an MCD or of flash flooding for an MPD
+	Watches          []MCDWatch      `json:"watches"`              // Every watch mentioned in the MCD
+	Summary          string          `json:"summary"`
+	Discussion       string          `json:"discussion"`
+	Forecaster       string          `json:"forecaster"`
+	TornadoIntensity string          `json:"tornado_intensity,omitempty"`
+	WindGust         string          `json:"wind_gust,omitempty"`
+	HailSize         string          `json:"hail_size,omitempty"`
 }
 
-func ParseMCD(product *Product) (*MCD, error) {
+// The paragraphs of every segment of the discussion in order
+func mcdParagraphs(segments []Segment) []TextSpan {
+	paragraphs := []TextSpan{}
+	for _, segment := range segments {
+		paragraphs = append(paragraphs, segment.Paragraphs...)
+	}
+	return paragraphs
+}
+
+// Get the submatches of the first paragraph the regexp matches and the index of that paragraph
+func mcdFind(paragraphs []TextSpan, r *regexp.Regexp) ([]string, int) {
+	for i, paragraph := range paragraphs {
+		if match := r.FindStringSubmatch(paragraph.Text); match != nil {
+			return match, i
+		}
+	}
+	return nil, -1
+}
+
+// Get the text of the paragraph that starts with the label, joined onto one line
+func mcdSection(paragraphs []TextSpan, label string) string {
+	match, _ := mcdFind(paragraphs, regexp.MustCompile(`(?s:^`+label+`\.\.\.(.*))`))
+	if match == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(match[1]), " ")
+}
 
-	idRegexp := regexp.MustCompile(`(Mesoscale Discussion [0-9]{1,4})`)
-	idString := idRegexp.FindString(product.Text)
-	if idString == "" {
-		return nil, errors.New("failed to find a MCD ID string")
+func mcdConcerningType(concerning string) string {
+	lower := strings.ToLower(concerning)
+	for _, c := range mcdConcerningTypes {
+		if strings.HasPrefix(lower, c.Prefix) {
+			return c.Type
+		}
 	}
+	return MCDOther
+}
+
+/*
+Find every watch named in the concerning and probability of watch issuance lines. Numbers belong to the watch
+type before them so "Tornado Watch 123...124" and "Severe Thunderstorm Watch 125, 126" are read correctly.
+The discussion is left out as it often talks about watches nearby or ones that have ended.
+*/
+func mcdWatches(text string, year int) []MCDWatch {
+	text = strings.Join(strings.Fields(text), " ")
+	watchRegexp := regexp.MustCompile(`(?i:(Severe Thunderstorm|Tornado) Watch(?:es)?((?:(?:\.\.\.|,| and| &|\s)*[0-9]{1,4}\b)+))`)
+	numberRegexp := regexp.MustCompile(`[0-9]+`)
 
-	numberSplit := strings.Split(idString, " ")
-	if len(numberSplit) != 3 {
-		return nil, errors.New("failed to find MCD ID string (wrong length)")
+	watches := []MCDWatch{}
+	found := map[string]bool{}
+	for _, match := range watchRegexp.FindAllStringSubmatch(text, -1) {
+		phenomena := "SV"
+		if strings.EqualFold(match[1], "tornado") {
+			phenomena = "TO"
+		}
+		for _, n := range numberRegexp.FindAllString(match[2], -1) {
+			number, err := strconv.Atoi(n)
+			if err != nil {
+				continue
+			}
+			id := WatchID(phenomena, number, year)
+			if found[id] {
+				continue
+			}
+			found[id] = true
+			watches = append(watches, MCDWatch{
+				ID:     id,
+				Type:   phenomena,
+				Number: number,
+			})
+		}
 	}
+	return watches
+}
 
-	numberString := numberSplit[2]
-	number, err := strconv.Atoi(numberString)
+// LedTo returns true if the watch was issued while the MCD was valid and covers part of its area
+func (m *MCD) LedTo(watch *Watch) bool {
+	if watch.Issued == nil {
+		return false
+	}
+	return m.Overlaps(*watch.Issued, watch.Polygon)
+}
+
+// Overlaps returns true if something issued at t while the discussion was valid covers part of its area
+func (m *MCD) Overlaps(t time.Time, polygon *PolygonFeature) bool {
+	if t.Before(m.Issued) || t.After(m.Expires) {
+		return false
+	}
+	return PolygonsOverlap(m.Polygon, polygon)
+}
+
+// ParseMCD parses the SPC's mesoscale discussions (SWOMCD)
+func ParseMCD(product *Product) (*MCD, error) {
+	return parseMesoscaleDiscussion(product, "MCD")
+}
+
+// ParseMPD parses the WPC's mesoscale precipitation discussions (FFGMPD) which share the layout of the MCD
+func ParseMPD(product *Product) (*MCD, error) {
+	return parseMesoscaleDiscussion(product, "MPD")
+}
+
+func parseMesoscaleDiscussion(product *Product, kind string) (*MCD, error) {
+
+	segments := product.Segments()
+	paragraphs := mcdParagraphs(segments)
+
+	idRegexp := regexp.MustCompile(`Mesoscale (?:Precipitation )?Discussion ([0-9]{1,4})`)
+	idMatch, _ := mcdFind(paragraphs, idRegexp)
+	if idMatch == nil {
+		return nil, errors.New("failed to find a " + kind + " ID string")
+	}
+
+	number, err := strconv.Atoi(idMatch[1])
 	if err != nil {
 		return nil, err
 	}
 
 	dateLineRegexp := regexp.MustCompile(`([0-9]{6}Z - [0-9]{6}Z)`)
-	dateLine := dateLineRegexp.FindString(product.Text)
+	dateLine := ""
+	if match, _ := mcdFind(paragraphs, dateLineRegexp); match != nil {
+		dateLine = match[1]
+	}
 	if dateLine == "" {
-		return nil, errors.New("failed to find date line in MCD")
+		return nil, errors.New("failed to find date line in " + kind)
 	}
 
 	dateLineSplit := strings.Split(dateLine, " - ")
 	if len(dateLineSplit) != 2 {
-		return nil, errors.New("date line does not have two dates in MCD")
+		return nil, errors.New("date line does not have two dates in " + kind)
 	}
 
 	layout := "021504Z"
@@ -76,17 +229,28 @@ func ParseMCD(product *Product) (*MCD, error) {
 	start := time.Date(product.Issued.Year(), product.Issued.Month(), startT.Day(), startT.Hour(), startT.Minute(), 0, 0, time.Now().UTC().Location())
 	end := time.Date(year, month, endT.Day(), endT.Hour(), endT.Minute(), 0, 0, time.Now().UTC().Location())
 
-	latlon, err := ParseLatLon(product.Text)
-	if err != nil {
-		return nil, err
+	var polygon *PolygonFeature
+	for _, segment := range segments {
+		if segment.LatLon == nil {
+			continue
+		}
+		latlon, err := ParseLatLon(segment.LatLon.Text)
+		if err != nil {
+			return nil, err
+		}
+		if latlon != nil {
+			polygon = latlon.Polygon
+			break
+		}
 	}
 
-	polygon := latlon.Polygon
-
 	watch := 0
 
 	watchLineRegexp := regexp.MustCompile("Probability of Watch Issuance...[0-9]+ percent")
-	watchLine := watchLineRegexp.FindString(product.Text)
+	watchLine := ""
+	if match, _ := mcdFind(paragraphs, watchLineRegexp); match != nil {
+		watchLine = match[0]
+	}
 	if watchLine != "" {
 		percentRegexp := regexp.MustCompile("[0-9]+")
 		percentString := percentRegexp.FindString(watchLine)
@@ -100,16 +264,65 @@ func ParseMCD(product *Product) (*MCD, error) {
 		}
 	}
 
-	id := "MCD" + util.PadZero(strconv.Itoa(number), 4) + strconv.Itoa(start.Year())
+	id := kind + util.PadZero(strconv.Itoa(number), 4) + strconv.Itoa(start.Year())
+
+	concerning := mcdSection(paragraphs, "Concerning")
+
+	likelihood := ""
+	likelihoodRegexp := regexp.MustCompile(`(?i:(?:Watch|Flash flooding) (likely|possible|unlikely))`)
+	if match := likelihoodRegexp.FindStringSubmatch(concerning); match != nil {
+		likelihood = strings.ToLower(match[1])
+	}
+
+	/*
+		The SPC signs off with "..Name.. MM/DD/YYYY" while the WPC puts the name in its own paragraph above the ATTN lines.
+		The discussion runs up to the signature.
+	*/
+	forecaster := ""
+	signature := len(product.Text)
+	if match, i := mcdFind(paragraphs, regexp.MustCompile(`^\.\.(.+?)\.\.\s+[0-9]{2}/[0-9]{2}/[0-9]{4}`)); match != nil {
+		forecaster = strings.TrimSpace(match[1])
+		signature = paragraphs[i].Start
+	} else if _, i := mcdFind(paragraphs, regexp.MustCompile(`^ATTN\.\.\.`)); i > 0 && !strings.Contains(paragraphs[i-1].Text, "\n") {
+		forecaster = paragraphs[i-1].Text
+		signature = paragraphs[i-1].Start
+	}
+
+	discussion := ""
+	if _, i := mcdFind(paragraphs, regexp.MustCompile(`^DISCUSSION\.\.\.`)); i >= 0 {
+		if start := paragraphs[i].Start + len("DISCUSSION..."); start < signature {
+			discussion = strings.TrimSpace(product.Text[start:signature])
+		}
+	}
+
+	intensity := func(label string) string {
+		intensityRegexp := regexp.MustCompile(`(?m:^MOST PROBABLE PEAK ` + label + `\.\.\.([^\n]+))`)
+		if match, _ := mcdFind(paragraphs, intensityRegexp); match != nil {
+			return strings.TrimSpace(match[1])
+		}
+		return ""
+	}
 
 	mcd := MCD{
 		ID:               id,
+		Type:             kind,
 		Original:         product.Text,
 		Number:           number,
 		Issued:           start,
 		Expires:          end,
 		Polygon:          polygon,
 		WatchProbability: watch,
+		AreasAffected:    mcdSection(paragraphs, "Areas affected"),
+		Concerning:       concerning,
+		ConcerningType:   mcdConcerningType(concerning),
+		Likelihood:       likelihood,
+		Watches:          mcdWatches(concerning+"\n"+watchLine, start.Year()),
+		Summary:          mcdSection(paragraphs, "SUMMARY"),
+		Discussion:       discussion,
+		Forecaster:       forecaster,
+		TornadoIntensity: intensity("TORNADO INTENSITY"),
+		WindGust:         intensity("WIND GUST"),
+		HailSize:         intensity("HAIL SIZE"),
 	}
 
 	return &mcd, nil
@@ -0,0 +1,103 @@
+package parsers
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseMCDWatches(t *testing.T) {
+	text, err := os.ReadFile("testdata/SWOMCD.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mcd, err := ParseMCD(&Product{Text: string(text), Issued: testTime(t, "2024-05-21T20:45:00Z")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mcd.WatchProbability != 40 {
+		t.Errorf("got watch probability %d, want 40", mcd.WatchProbability)
+	}
+
+	// The watches in the summary and discussion are not what the MCD is concerning
+	want := []string{"TOA02502024", "TOA02512024", "SVA02492024"}
+	if len(mcd.Watches) != len(want) {
+		t.Fatalf("got watches %v, want %v", mcd.Watches, want)
+	}
+	for i, id := range want {
+		if mcd.Watches[i].ID != id {
+			t.Errorf("watch %d is %s, want %s", i, mcd.Watches[i].ID, id)
+		}
+	}
+}
+
+func TestParseMCD(t *testing.T) {
+	text, err := os.ReadFile("testdata/SWOMCD.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mcd, err := ParseMCD(&Product{Text: string(text), Issued: testTime(t, "2024-05-21T20:45:00Z")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id", mcd.ID, "MCD08122024"},
+		{"issued", mcd.Issued.Format(time.RFC3339), "2024-05-21T20:45:00Z"},
+		{"expires", mcd.Expires.Format(time.RFC3339), "2024-05-21T22:45:00Z"},
+		{"areas affected", mcd.AreasAffected, "Central Iowa into southern Minnesota"},
+		{"concerning", mcd.Concerning, "Severe potential...Tornado Watch 250...251... Severe Thunderstorm Watch 249"},
+		{"concerning type", mcd.ConcerningType, MCDSeverePotential},
+		{"summary", mcd.Summary, "The severe threat continues across Tornado Watch 250 and 251."},
+		{"discussion", mcd.Discussion, "Storms that moved out of Severe Thunderstorm Watch 247 earlier\nthis afternoon have weakened. Tornado Watch 248 expired at 20Z."},
+		{"forecaster", mcd.Forecaster, "Forecaster"},
+		{"tornado intensity", mcd.TornadoIntensity, "95-120 MPH"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s is %q, want %q", test.name, test.got, test.want)
+		}
+	}
+
+	if mcd.Polygon == nil || len(mcd.Polygon.Coordinates[0]) != 5 {
+		t.Errorf("got polygon %v, want the 5 points of the LAT...LON", mcd.Polygon)
+	}
+}
+
+func TestParseMPD(t *testing.T) {
+	text, err := os.ReadFile("testdata/FFGMPD.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mpd, err := ParseMPD(&Product{Text: string(text), Issued: testTime(t, "2024-05-21T21:00:00Z")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id", mpd.ID, "MPD02502024"},
+		{"expires", mpd.Expires.Format(time.RFC3339), "2024-05-22T03:00:00Z"},
+		{"concerning type", mpd.ConcerningType, MCDHeavyRainfall},
+		{"likelihood", mpd.Likelihood, "possible"},
+		{"summary", mpd.Summary, "Training thunderstorms may produce 2 to 3 inches of rain over the next few hours."},
+		{"discussion", mpd.Discussion, "Storms are training along a stalled front.\n\n...Flash flooding is most likely near Ames..."},
+		{"forecaster", mpd.Forecaster, "Hurley"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s is %q, want %q", test.name, test.got, test.want)
+		}
+	}
+}
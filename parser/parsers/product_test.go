@@ -0,0 +1,37 @@
+package parsers
+
+import "testing"
+
+func TestNewAWIPSProductWithoutAWIPS(t *testing.T) {
+	text := "\x01\n123\nSXUS70 KWBC 142200\nRTP RAINFALL SUMMARY\n\x03"
+
+	product, err := NewAWIPSProduct(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product == nil {
+		t.Fatal("a product without an AWIPS line should still be made")
+	}
+	if product.AWIPS.Original != "" {
+		t.Errorf("AWIPS is %q, want none", product.AWIPS.Original)
+	}
+	if product.WFO != "WBC" {
+		t.Errorf("WFO is %s, want WBC", product.WFO)
+	}
+	if product.Family != HeaderFamilyBulletin {
+		t.Errorf("family is %s, want %s", product.Family, HeaderFamilyBulletin)
+	}
+	if want := "KWBCSXUS70" + product.Issued.Format("200601021504"); product.Group != want {
+		t.Errorf("group is %s, want %s", product.Group, want)
+	}
+}
+
+func TestNewAWIPSProductOther(t *testing.T) {
+	product, err := NewAWIPSProduct("\x01\n456\nHRAE89 KWBC 142200\n\x03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product == nil || product.Family != HeaderFamilyOther {
+		t.Fatal("gridded products should be made with the other family")
+	}
+}
@@ -0,0 +1,681 @@
+package parsers
+
+import (
+	"math"
+	"sort"
+)
+
+// How far apart the ends of a contour can be and still be treated as a closed ring
+const ptsClosedTolerance = 0.01
+
+// How far a vertex is nudged when it sits exactly on the edge of the other polygon while clipping
+const clipPerturbation = 1e-7
+
+/*
+Everything in here works on rings of [lon, lat] points. Open rings don't repeat the first point at
+the end, closed rings do. Clockwise rings have a negative signed area.
+*/
+
+func ringArea(ring [][2]float64) float64 {
+	area := 0.0
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		a := ring[i]
+		b := ring[(i+1)%n]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area / 2
+}
+
+func openRing(ring [][2]float64) [][2]float64 {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		return ring[:len(ring)-1]
+	}
+	return ring
+}
+
+func closeRing(ring [][2]float64) [][2]float64 {
+	ring = append([][2]float64{}, ring...)
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+	return ring
+}
+
+func reverseRing(ring [][2]float64) [][2]float64 {
+	reversed := make([][2]float64, len(ring))
+	for i, p := range ring {
+		reversed[len(ring)-1-i] = p
+	}
+	return reversed
+}
+
+// PointInRing tests if a point is inside a ring using ray casting. The ring can be open or closed
+func PointInRing(point [2]float64, ring [][2]float64) bool {
+	inside := false
+	n := len(ring)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a := ring[i]
+		b := ring[j]
+		if (a[1] > point[1]) != (b[1] > point[1]) &&
+			point[0] < (b[0]-a[0])*(point[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// PointInPolygon tests a point against the outer ring and holes of a polygon
+func PointInPolygon(point [2]float64, polygon [][][2]float64) bool {
+	if len(polygon) == 0 || !PointInRing(point, polygon[0]) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if PointInRing(point, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// PointInMultiPolygon tests a point against every polygon in the feature
+func PointInMultiPolygon(point [2]float64, multi *MultiPolygonFeature) bool {
+	if multi == nil {
+		return false
+	}
+	for _, polygon := range multi.Coordinates {
+		if PointInPolygon(point, polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Find where p1->p2 crosses q1->q2. t is how far along p and u how far along q the crossing is.
+ok is false for parallel lines.
+*/
+func segmentIntersection(p1, p2, q1, q2 [2]float64) (float64, float64, bool) {
+	r := [2]float64{p2[0] - p1[0], p2[1] - p1[1]}
+	s := [2]float64{q2[0] - q1[0], q2[1] - q1[1]}
+	denominator := r[0]*s[1] - r[1]*s[0]
+	if math.Abs(denominator) < 1e-15 {
+		return 0, 0, false
+	}
+	qp := [2]float64{q1[0] - p1[0], q1[1] - p1[1]}
+	t := (qp[0]*s[1] - qp[1]*s[0]) / denominator
+	u := (qp[0]*r[1] - qp[1]*r[0]) / denominator
+	return t, u, true
+}
+
+func pointOnSegment(p, a, b [2]float64) bool {
+	cross := (b[0]-a[0])*(p[1]-a[1]) - (b[1]-a[1])*(p[0]-a[0])
+	length := math.Hypot(b[0]-a[0], b[1]-a[1])
+	if length == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1]) < clipPerturbation
+	}
+	if math.Abs(cross)/length > clipPerturbation {
+		return false
+	}
+	dot := (p[0]-a[0])*(b[0]-a[0]) + (p[1]-a[1])*(b[1]-a[1])
+	return dot >= -clipPerturbation && dot <= length*length+clipPerturbation
+}
+
+func lerp(a, b [2]float64, t float64) [2]float64 {
+	return [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+}
+
+type boundaryCrossing struct {
+	segment int
+	t       float64
+	edge    int
+	u       float64
+	point   [2]float64
+}
+
+// Cast a ray from origin in the given direction and return the nearest point it hits the boundary
+func rayToBoundary(origin [2]float64, direction [2]float64, boundary [][2]float64) ([2]float64, bool) {
+	best := math.Inf(1)
+	far := [2]float64{origin[0] + direction[0], origin[1] + direction[1]}
+	n := len(boundary)
+	for e := 0; e < n; e++ {
+		t, u, ok := segmentIntersection(origin, far, boundary[e], boundary[(e+1)%n])
+		if ok && t > 0 && u >= 0 && u <= 1 && t < best {
+			best = t
+		}
+	}
+	if math.IsInf(best, 1) {
+		return origin, false
+	}
+	return lerp(origin, far, best), true
+}
+
+/*
+Turn an open contour into a ring using the right-hand rule: the area to the right of the line is inside.
+The line is trimmed to where it first enters and last leaves the boundary, ends that stop short of the
+boundary are extended out to it, and the ring is closed by following the clockwise boundary from the exit
+back round to the entry.
+*/
+func closeLineAgainstBoundary(line [][2]float64, boundary [][2]float64) ([][2]float64, bool) {
+	if len(line) < 2 {
+		return nil, false
+	}
+	line = append([][2]float64{}, line...)
+
+	if PointInRing(line[0], boundary) {
+		direction := [2]float64{line[0][0] - line[1][0], line[0][1] - line[1][1]}
+		if p, ok := rayToBoundary(line[0], direction, boundary); ok {
+			line = append([][2]float64{p}, line...)
+		}
+	}
+	last := len(line) - 1
+	if PointInRing(line[last], boundary) {
+		direction := [2]float64{line[last][0] - line[last-1][0], line[last][1] - line[last-1][1]}
+		if p, ok := rayToBoundary(line[last], direction, boundary); ok {
+			line = append(line, p)
+		}
+	}
+
+	crossings := []boundaryCrossing{}
+	n := len(boundary)
+	for i := 0; i < len(line)-1; i++ {
+		for e := 0; e < n; e++ {
+			t, u, ok := segmentIntersection(line[i], line[i+1], boundary[e], boundary[(e+1)%n])
+			// The ends extended out to the boundary sit right on it so allow for rounding
+			if ok && t >= -clipPerturbation && t <= 1+clipPerturbation && u >= -clipPerturbation && u <= 1+clipPerturbation {
+				crossings = append(crossings, boundaryCrossing{
+					segment: i,
+					t:       t,
+					edge:    e,
+					u:       u,
+					point:   lerp(line[i], line[i+1], t),
+				})
+			}
+		}
+	}
+	if len(crossings) < 2 {
+		return nil, false
+	}
+
+	sort.Slice(crossings, func(i, j int) bool {
+		if crossings[i].segment != crossings[j].segment {
+			return crossings[i].segment < crossings[j].segment
+		}
+		return crossings[i].t < crossings[j].t
+	})
+
+	entry := crossings[0]
+	exit := crossings[len(crossings)-1]
+
+	ring := [][2]float64{entry.point}
+	for i := entry.segment + 1; i <= exit.segment; i++ {
+		ring = append(ring, line[i])
+	}
+	ring = append(ring, exit.point)
+
+	// Walk the boundary clockwise from the exit back to the entry
+	if !(entry.edge == exit.edge && entry.u > exit.u) {
+		k := (exit.edge + 1) % n
+		for {
+			ring = append(ring, boundary[k])
+			if k == entry.edge {
+				break
+			}
+			k = (k + 1) % n
+		}
+	}
+
+	return closeRing(ring), true
+}
+
+type ghVertex struct {
+	point     [2]float64
+	next      *ghVertex
+	prev      *ghVertex
+	neighbour *ghVertex
+	alpha     float64
+	intersect bool
+	entry     bool
+	visited   bool
+}
+
+func ghList(ring [][2]float64) []*ghVertex {
+	vertices := make([]*ghVertex, len(ring))
+	for i, p := range ring {
+		vertices[i] = &ghVertex{point: p}
+	}
+	for i, v := range vertices {
+		v.next = vertices[(i+1)%len(vertices)]
+		v.prev = vertices[(i-1+len(vertices))%len(vertices)]
+	}
+	return vertices
+}
+
+// Insert an intersection after start, keeping the intersections on the edge in order
+func ghInsert(start *ghVertex, v *ghVertex) {
+	current := start
+	for current.next.intersect && current.next.alpha < v.alpha {
+		current = current.next
+	}
+	v.next = current.next
+	v.prev = current
+	current.next.prev = v
+	current.next = v
+}
+
+/*
+Nudge any vertex of subject that sits on an edge of the clip polygon. Greiner-Hormann can't cope with
+vertices that touch the other polygon so this moves them by a distance far smaller than the
+precision the outlooks are issued at.
+*/
+func perturbRing(subject [][2]float64, clip [][2]float64) [][2]float64 {
+	result := append([][2]float64{}, subject...)
+	n := len(clip)
+	for i, p := range result {
+		for e := 0; e < n; e++ {
+			if pointOnSegment(p, clip[e], clip[(e+1)%n]) {
+				result[i] = [2]float64{p[0] + clipPerturbation*3, p[1] + clipPerturbation*7}
+				break
+			}
+		}
+	}
+	return result
+}
+
+/*
+Clip two simple polygons with the Greiner-Hormann algorithm, returning the intersection or, when
+union is true, the union. The rings are open and the result rings are closed.
+*/
+func clipRings(subject [][2]float64, clip [][2]float64, union bool) [][][2]float64 {
+	subject = perturbRing(openRing(subject), openRing(clip))
+	clip = perturbRing(openRing(clip), subject)
+
+	sv := ghList(subject)
+	cv := ghList(clip)
+
+	intersections := 0
+	for i := range sv {
+		s1 := sv[i]
+		s2 := sv[(i+1)%len(sv)]
+		for j := range cv {
+			c1 := cv[j]
+			c2 := cv[(j+1)%len(cv)]
+			a, b, ok := segmentIntersection(s1.point, s2.point, c1.point, c2.point)
+			if !ok || a <= 0 || a >= 1 || b <= 0 || b >= 1 {
+				continue
+			}
+			point := lerp(s1.point, s2.point, a)
+			is := &ghVertex{point: point, alpha: a, intersect: true}
+			ic := &ghVertex{point: point, alpha: b, intersect: true}
+			is.neighbour = ic
+			ic.neighbour = is
+			ghInsert(s1, is)
+			ghInsert(c1, ic)
+			intersections++
+		}
+	}
+
+	if intersections == 0 {
+		subjectInside := PointInRing(subject[0], clip)
+		clipInside := PointInRing(clip[0], subject)
+		switch {
+		case subjectInside && union:
+			return [][][2]float64{closeRing(clip)}
+		case subjectInside:
+			return [][][2]float64{closeRing(subject)}
+		case clipInside && union:
+			return [][][2]float64{closeRing(subject)}
+		case clipInside:
+			return [][][2]float64{closeRing(clip)}
+		case union:
+			return [][][2]float64{closeRing(subject), closeRing(clip)}
+		}
+		return [][][2]float64{}
+	}
+
+	// Mark each intersection as an entry into or exit from the other polygon
+	status := !PointInRing(sv[0].point, clip)
+	if union {
+		status = !status
+	}
+	for v := sv[0].next; v != sv[0]; v = v.next {
+		if v.intersect {
+			v.entry = status
+			status = !status
+		}
+	}
+	status = !PointInRing(cv[0].point, subject)
+	if union {
+		status = !status
+	}
+	for v := cv[0].next; v != cv[0]; v = v.next {
+		if v.intersect {
+			v.entry = status
+			status = !status
+		}
+	}
+
+	rings := [][][2]float64{}
+	for start := sv[0].next; start != sv[0]; start = start.next {
+		if !start.intersect || start.visited {
+			continue
+		}
+
+		ring := [][2]float64{start.point}
+		current := start
+		for !current.visited {
+			current.visited = true
+			current.neighbour.visited = true
+			if current.entry {
+				for {
+					current = current.next
+					ring = append(ring, current.point)
+					if current.intersect {
+						break
+					}
+				}
+			} else {
+				for {
+					current = current.prev
+					ring = append(ring, current.point)
+					if current.intersect {
+						break
+					}
+				}
+			}
+			current = current.neighbour
+		}
+
+		ring = closeRing(ring)
+		if len(ring) >= 4 {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
+
+func pointOnRing(point [2]float64, ring [][2]float64) bool {
+	n := len(ring)
+	for i := 0; i < n; i++ {
+		if pointOnSegment(point, ring[i], ring[(i+1)%n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func ringsCross(a [][2]float64, b [][2]float64) bool {
+	for i := range a {
+		for j := range b {
+			t, u, ok := segmentIntersection(a[i], a[(i+1)%len(a)], b[j], b[(j+1)%len(b)])
+			if ok && t > 1e-9 && t < 1-1e-9 && u > 1e-9 && u < 1-1e-9 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+Whether inner sits inside outer, allowing the two to share edges such as a stretch of the boundary.
+Edge midpoints are checked as well as the vertices to catch concave outer rings.
+*/
+func ringWithin(inner [][2]float64, outer [][2]float64) bool {
+	inner = openRing(inner)
+	outer = openRing(outer)
+	if ringsCross(inner, outer) {
+		return false
+	}
+	n := len(inner)
+	for i := 0; i < n; i++ {
+		mid := lerp(inner[i], inner[(i+1)%n], 0.5)
+		for _, p := range [][2]float64{inner[i], mid} {
+			if !PointInRing(p, outer) && !pointOnRing(p, outer) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Whether two rings cross, touch or one sits inside the other
+func ringsOverlap(a [][2]float64, b [][2]float64) bool {
+	a = openRing(a)
+	b = openRing(b)
+	if ringsCross(a, b) {
+		return true
+	}
+	for _, p := range a {
+		if PointInRing(p, b) || pointOnRing(p, b) {
+			return true
+		}
+	}
+	for _, p := range b {
+		if PointInRing(p, a) || pointOnRing(p, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func clipToBoundary(ring [][2]float64, boundary [][2]float64) [][][2]float64 {
+	if ringWithin(ring, boundary) {
+		return [][][2]float64{closeRing(ring)}
+	}
+	return clipRings(ring, boundary, false)
+}
+
+type ptsPolygon struct {
+	outer [][2]float64
+	holes [][][2]float64
+}
+
+/*
+PTSLinesToPolygons converts the contours of an outlook category into valid polygons.
+Open contours are closed against the boundary using the right-hand rule. Closed contours drawn clockwise
+are areas, those drawn anticlockwise cut holes out of the area around them (or out of the whole boundary
+if nothing surrounds them). Everything is clipped to the boundary and overlapping areas are merged so
+the polygons in the result don't overlap.
+*/
+func PTSLinesToPolygons(lines [][][2]float64, boundary [][2]float64) *MultiPolygonFeature {
+	boundary = openRing(boundary)
+	if ringArea(boundary) > 0 {
+		boundary = reverseRing(boundary)
+	}
+
+	outers := [][][2]float64{}
+	holes := [][][2]float64{}
+
+	for _, line := range lines {
+		if len(line) < 2 {
+			continue
+		}
+		first := line[0]
+		last := line[len(line)-1]
+		closed := len(line) >= 4 && math.Hypot(first[0]-last[0], first[1]-last[1]) < ptsClosedTolerance
+
+		if closed {
+			ring := openRing(line)
+			if ringArea(ring) > 0 {
+				holes = append(holes, closeRing(ring))
+				continue
+			}
+			outers = append(outers, clipToBoundary(ring, boundary)...)
+			continue
+		}
+
+		ring, ok := closeLineAgainstBoundary(line, boundary)
+		if !ok {
+			// The line never reaches the boundary so all we can do is close it on itself
+			ring = closeRing(line)
+			if len(ring) < 4 {
+				continue
+			}
+			if ringArea(openRing(ring)) > 0 {
+				ring = reverseRing(ring)
+			}
+			outers = append(outers, clipToBoundary(ring, boundary)...)
+			continue
+		}
+		outers = append(outers, ring)
+	}
+
+	polygons := []ptsPolygon{}
+	for _, outer := range outers {
+		polygons = append(polygons, ptsPolygon{outer: outer})
+	}
+
+	// Holes with nothing around them are cut out of the whole boundary
+	for _, hole := range holes {
+		found := false
+		for i := range polygons {
+			if PointInRing(hole[0], polygons[i].outer) {
+				polygons[i].holes = append(polygons[i].holes, hole)
+				found = true
+				break
+			}
+		}
+		if !found {
+			polygons = append(polygons, ptsPolygon{
+				outer: closeRing(boundary),
+				holes: [][][2]float64{hole},
+			})
+		}
+	}
+
+	// Merge overlapping areas until none are left
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(polygons) && !merged; i++ {
+			for j := i + 1; j < len(polygons) && !merged; j++ {
+				a := polygons[i]
+				b := polygons[j]
+				if !ringsOverlap(a.outer, b.outer) {
+					continue
+				}
+				if ringWithin(b.outer, a.outer) || ringWithin(a.outer, b.outer) {
+					if ringWithin(a.outer, b.outer) {
+						a, b = b, a
+					}
+					polygons[i] = ptsPolygon{
+						outer: a.outer,
+						holes: a.holes,
+					}
+					polygons = append(polygons[:j], polygons[j+1:]...)
+					merged = true
+					continue
+				}
+				rings := clipRings(polygons[i].outer, polygons[j].outer, true)
+				if len(rings) == 0 {
+					continue
+				}
+				sort.Slice(rings, func(a, b int) bool {
+					return math.Abs(ringArea(openRing(rings[a]))) > math.Abs(ringArea(openRing(rings[b])))
+				})
+				// Rings that only touch at a point stay apart, anything else the union gives back is a hole
+				separate := false
+				for _, ring := range rings[1:] {
+					if !ringWithin(ring, rings[0]) {
+						separate = true
+					}
+				}
+				if separate {
+					continue
+				}
+				polygon := ptsPolygon{
+					outer: rings[0],
+					holes: append(append(polygons[i].holes, polygons[j].holes...), rings[1:]...),
+				}
+				polygons[i] = polygon
+				polygons = append(polygons[:j], polygons[j+1:]...)
+				merged = true
+			}
+		}
+	}
+
+	// GeoJSON wants outer rings anticlockwise and holes clockwise
+	multi := MultiPolygonFeature{
+		Type:        "MultiPolygon",
+		Coordinates: [][][][2]float64{},
+	}
+	for _, polygon := range polygons {
+		outer := polygon.outer
+		if ringArea(openRing(outer)) < 0 {
+			outer = reverseRing(outer)
+		}
+		coordinates := [][][2]float64{outer}
+		for _, hole := range polygon.holes {
+			if ringArea(openRing(hole)) > 0 {
+				hole = reverseRing(hole)
+			}
+			coordinates = append(coordinates, hole)
+		}
+		multi.Coordinates = append(multi.Coordinates, coordinates)
+	}
+
+	return &multi
+}
+
+// Put a ring the way round GeoJSON wants it, anticlockwise for outer rings and clockwise for holes
+func orientRing(ring [][2]float64, outer bool) [][2]float64 {
+	if (ringArea(openRing(ring)) < 0) == outer {
+		return reverseRing(ring)
+	}
+	return ring
+}
+
+/*
+IntersectMultiPolygons returns the area covered by both a and b. Holes are kept where they sit inside the
+result, a hole that crosses the edge of the result is dropped along with the sliver it would have cut.
+*/
+func IntersectMultiPolygons(a *MultiPolygonFeature, b *MultiPolygonFeature) *MultiPolygonFeature {
+	multi := MultiPolygonFeature{
+		Type:        "MultiPolygon",
+		Coordinates: [][][][2]float64{},
+	}
+	if a == nil || b == nil {
+		return &multi
+	}
+
+	for _, pa := range a.Coordinates {
+		for _, pb := range b.Coordinates {
+			if len(pa) == 0 || len(pb) == 0 || !ringsOverlap(pa[0], pb[0]) {
+				continue
+			}
+
+			var rings [][][2]float64
+			switch {
+			case ringWithin(pa[0], pb[0]):
+				rings = [][][2]float64{pa[0]}
+			case ringWithin(pb[0], pa[0]):
+				rings = [][][2]float64{pb[0]}
+			default:
+				rings = clipRings(pa[0], pb[0], false)
+			}
+
+			for _, ring := range rings {
+				if math.Abs(ringArea(openRing(ring))) < clipPerturbation {
+					continue
+				}
+				polygon := [][][2]float64{orientRing(closeRing(ring), true)}
+				for _, hole := range append(append([][][2]float64{}, pa[1:]...), pb[1:]...) {
+					if ringWithin(hole, ring) {
+						polygon = append(polygon, orientRing(closeRing(hole), false))
+					}
+				}
+				multi.Coordinates = append(multi.Coordinates, polygon)
+			}
+		}
+	}
+
+	return &multi
+}
+
+// PolygonsOverlap returns true if the outer rings of two polygons cross, touch or one sits inside the other
+func PolygonsOverlap(a *PolygonFeature, b *PolygonFeature) bool {
+	if a == nil || b == nil || len(a.Coordinates) == 0 || len(b.Coordinates) == 0 {
+		return false
+	}
+	return ringsOverlap(a.Coordinates[0], b.Coordinates[0])
+}
@@ -0,0 +1,249 @@
+package parsers
+
+import (
+	"os"
+	"testing"
+)
+
+func testPTS(t *testing.T, name string) []PTS {
+	t.Helper()
+	text, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := NewAWIPSProduct(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outlooks, err := ParsePTSProduct(product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outlooks
+}
+
+func ptsCategory(t *testing.T, outlook PTS, segment string, category string) *PTSCategories {
+	t.Helper()
+	for _, s := range *outlook.Segments {
+		if s.Type != segment {
+			continue
+		}
+		for i := range *s.Categories {
+			if (*s.Categories)[i].Category == category {
+				return &(*s.Categories)[i]
+			}
+		}
+	}
+	t.Fatalf("outlook has no %s %s", segment, category)
+	return nil
+}
+
+// Every ring must be closed and long enough to be a polygon
+func checkRings(t *testing.T, name string, multi *MultiPolygonFeature) {
+	t.Helper()
+	for i, polygon := range multi.Coordinates {
+		for j, ring := range polygon {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				t.Errorf("%s polygon %d ring %d is not a closed ring", name, i, j)
+			}
+		}
+	}
+}
+
+func TestParsePTSProductCategories(t *testing.T) {
+	outlooks := testPTS(t, "PTSDY1.txt")
+	if len(outlooks) != 1 {
+		t.Fatalf("got %d outlooks, want 1", len(outlooks))
+	}
+	outlook := outlooks[0]
+
+	parts := map[string]int{
+		"TSTM": 2, // A line across the south and a ring in the northeast split by 99999
+		"MRGL": 1, // Both ends stop short of the coast
+		"SLGT": 1,
+		"ENH":  1, // Two rings sharing an edge are merged
+	}
+	for category, want := range parts {
+		c := ptsCategory(t, outlook, "CATEGORICAL", category)
+		checkRings(t, category, c.Features)
+		if len(c.Features.Coordinates) != want {
+			t.Errorf("%s has %d polygons, want %d", category, len(c.Features.Coordinates), want)
+		}
+	}
+
+	tests := []struct {
+		point [2]float64
+		want  []string
+	}{
+		{[2]float64{-110, 33}, []string{"TSTM", "MRGL"}},
+		{[2]float64{-73, 42.6}, []string{"TSTM"}},
+		{[2]float64{-95, 45}, []string{}},
+		// Above the MRGL line, where the SLGT ring was drawn past it
+		{[2]float64{-100.05, 40.55}, []string{"TSTM"}},
+		{[2]float64{-100.05, 38.55}, []string{"TSTM", "MRGL", "SLGT", "ENH"}},
+		// Either side of the edge the two ENH rings share
+		{[2]float64{-99.05, 38.5}, []string{"TSTM", "MRGL", "SLGT", "ENH"}},
+		{[2]float64{-98.95, 38.5}, []string{"TSTM", "MRGL", "SLGT", "ENH"}},
+		// The second ENH ring runs past the east side of the SLGT
+		{[2]float64{-97.75, 38.55}, []string{"TSTM", "MRGL"}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, match := range outlook.CategoriesAt(test.point) {
+			if match.Type == "CATEGORICAL" {
+				got = append(got, match.Category)
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%v is in %v, want %v", test.point, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v is in %v, want %v", test.point, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPTSCategoriesNest(t *testing.T) {
+	outlook := testPTS(t, "PTSDY1.txt")[0]
+
+	chains := []struct {
+		segment    string
+		categories []string
+	}{
+		{"CATEGORICAL", []string{"TSTM", "MRGL", "SLGT", "ENH"}},
+		{"TORNADO", []string{"0.02", "0.05"}},
+	}
+
+	for _, chain := range chains {
+		for i := 1; i < len(chain.categories); i++ {
+			outer := ptsCategory(t, outlook, chain.segment, chain.categories[i-1])
+			inner := ptsCategory(t, outlook, chain.segment, chain.categories[i])
+			// Sample off the whole degrees so no point lands on an edge
+			for lon := -124.87; lon < -67; lon += 0.25 {
+				for lat := 24.93; lat < 49.5; lat += 0.25 {
+					point := [2]float64{lon, lat}
+					if PointInMultiPolygon(point, inner.Features) && !PointInMultiPolygon(point, outer.Features) {
+						t.Fatalf("%v is in %s but not %s", point, inner.Category, outer.Category)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPTSLinesToPolygonsEdges(t *testing.T) {
+	// Clockwise rings are areas
+	block := [][2]float64{{-101, 39}, {-99, 39}, {-99, 38}, {-101, 38}, {-101, 39}}
+
+	tests := []struct {
+		name     string
+		lines    [][][2]float64
+		polygons int
+		inside   [][2]float64
+		outside  [][2]float64
+	}{
+		{
+			name:     "ring along the Canadian border",
+			lines:    [][][2]float64{{{-110, 49}, {-100, 49}, {-100, 46}, {-110, 46}, {-110, 49}}},
+			polygons: 1,
+			inside:   [][2]float64{{-105, 47.5}},
+			outside:  [][2]float64{{-105, 45.5}},
+		},
+		{
+			name:     "rings sharing an edge",
+			lines:    [][][2]float64{block, {{-99, 39}, {-97.5, 39}, {-97.5, 38}, {-99, 38}, {-99, 39}}},
+			polygons: 1,
+			inside:   [][2]float64{{-100, 38.5}, {-98, 38.5}},
+			outside:  [][2]float64{{-97, 38.5}},
+		},
+		{
+			name:     "rings touching at a corner",
+			lines:    [][][2]float64{block, {{-99, 38}, {-98, 38}, {-98, 37}, {-99, 37}, {-99, 38}}},
+			polygons: 2,
+			inside:   [][2]float64{{-100, 38.5}, {-98.5, 37.5}},
+			outside:  [][2]float64{{-98.5, 38.5}, {-100, 37.5}},
+		},
+		{
+			name:     "line from a boundary vertex to the Canadian border",
+			lines:    [][][2]float64{{{-97.1, 27.2}, {-98, 35}, {-100, 49}}},
+			polygons: 1,
+			inside:   [][2]float64{{-90, 40}},
+			outside:  [][2]float64{{-105, 40}},
+		},
+		{
+			name:     "anticlockwise ring cuts a hole",
+			lines:    [][][2]float64{block, {{-100.5, 38.8}, {-100.5, 38.2}, {-99.5, 38.2}, {-99.5, 38.8}, {-100.5, 38.8}}},
+			polygons: 1,
+			inside:   [][2]float64{{-100.8, 38.5}},
+			outside:  [][2]float64{{-100, 38.5}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			multi := PTSLinesToPolygons(test.lines, CONUS)
+			checkRings(t, test.name, multi)
+			if len(multi.Coordinates) != test.polygons {
+				t.Errorf("got %d polygons, want %d", len(multi.Coordinates), test.polygons)
+			}
+			for _, p := range test.inside {
+				if !PointInMultiPolygon(p, multi) {
+					t.Errorf("%v should be inside", p)
+				}
+			}
+			for _, p := range test.outside {
+				if PointInMultiPolygon(p, multi) {
+					t.Errorf("%v should be outside", p)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePTSProductSections(t *testing.T) {
+	outlook := testPTS(t, "PTSDY1.txt")[0]
+
+	// Each outlook closed by && is kept, whichever day line it falls under
+	want := []string{"TORNADO", "HAIL", "CATEGORICAL"}
+	if len(*outlook.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %v", len(*outlook.Segments), want)
+	}
+	for i, name := range want {
+		if (*outlook.Segments)[i].Type != name {
+			t.Errorf("segment %d is %s, want %s", i, (*outlook.Segments)[i].Type, name)
+		}
+	}
+}
+
+func TestParsePTSProductDays(t *testing.T) {
+	outlooks := testPTS(t, "PTSD48.txt")
+
+	want := []struct {
+		day        int
+		start      string
+		categories int
+	}{
+		{4, "2024-05-18T12:00:00Z", 1},
+		{5, "2024-05-19T12:00:00Z", 0},
+		{6, "2024-05-20T12:00:00Z", 2},
+	}
+	if len(outlooks) != len(want) {
+		t.Fatalf("got %d outlooks, want %d", len(outlooks), len(want))
+	}
+	for i, w := range want {
+		outlook := outlooks[i]
+		if outlook.Day != w.day {
+			t.Errorf("outlook %d is day %d, want %d", i, outlook.Day, w.day)
+		}
+		if got := outlook.Start.Format("2006-01-02T15:04:05Z07:00"); got != w.start {
+			t.Errorf("day %d starts %s, want %s", w.day, got, w.start)
+		}
+		if len(*outlook.Segments) != 1 || len(*(*outlook.Segments)[0].Categories) != w.categories {
+			t.Errorf("day %d has segments %v, want one with %d categories", w.day, *outlook.Segments, w.categories)
+		}
+	}
+}
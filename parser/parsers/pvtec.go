@@ -29,6 +29,23 @@ type PVTEC struct {
 
 const TYPES = "OTEX"
 
+// How long after a previous year's event has ended that it can still be continued into the new year
+const VTECRolloverWindow = 7 * 24 * time.Hour
+
+// Phenomena and significance pairs numbered nationally rather than by each office
+var nationalETNs = map[string]bool{
+	"TO.A": true,
+	"SV.A": true,
+}
+
+// Tropical phenomena use the national storm number as the ETN regardless of significance
+var tropicalPhenomena = map[string]bool{
+	"HU": true,
+	"TR": true,
+	"SS": true,
+	"TY": true,
+}
+
 func FindPVTEC(text string) int {
 	vtecRegex := regexp.MustCompile(`([A-Z])\.([A-Z]+)\.([A-Z]+)\.([A-Z]+)\.([A-Z])\.([0-9]+)\.([0-9TZ]+)-([0-9TZ]+)`)
 	result := vtecRegex.FindAllString(text, -1)
@@ -75,93 +92,26 @@ func ParsePVTEC(text string, issued time.Time, ugc UGC) ([]PVTEC, error) {
 		wfo := segments[2]
 		wfo = wfo[1:]
 
+		catalog := GetHazardCatalog()
+
 		// Get phenomena
 		phenomena := segments[3]
-		allowedPhenomena := [...]string{
-			"AF",
-			"AS",
-			"BH",
-			"BW",
-			"BZ",
-			"CF",
-			"DF",
-			"DS",
-			"EC",
-			"EH",
-			"EW",
-			"FA",
-			"FF",
-			"FG",
-			"FL",
-			"FR",
-			"FW",
-			"FZ",
-			"GL",
-			"HF",
-			"HT",
-			"HU",
-			"HW",
-			"HY",
-			"HZ",
-			"IS",
-			"LE",
-			"LO",
-			"LS",
-			"LW",
-			"MA",
-			"MF",
-			"MH",
-			"MS",
-			"RB",
-			"RP",
-			"SC",
-			"SE",
-			"SI",
-			"SM",
-			"SQ",
-			"SR",
-			"SS",
-			"SU",
-			"SV",
-			"SW",
-			"TO",
-			"TR",
-			"TS",
-			"TY",
-			"UP",
-			"WC",
-			"WI",
-			"WS",
-			"WW",
-			"ZF",
-			"ZR",
-		}
-
-		result = false
-		for _, a := range allowedPhenomena {
-			if a == phenomena {
-				result = true
-			}
-		}
-
-		if !result {
+		if !catalog.ValidPhenomena(phenomena) {
 			return vtecs, errors.New("invalid VTEC phenomena")
 		}
+		if !catalog.ValidPhenomenaAt(phenomena, issued) {
+			return vtecs, errors.New("VTEC phenomena " + phenomena + " is not in effect yet")
+		}
 
 		// Get significance
 		significance := segments[4]
-		allowedSignificance := [7]string{"W", "A", "Y", "S", "F", "O", "N"}
-
-		result = false
-		for _, a := range allowedSignificance {
-			if a == significance {
-				result = true
-			}
-		}
-
-		if !result {
+		if !catalog.ValidSignificance(significance) {
 			return vtecs, errors.New("invalid VTEC significance")
 		}
+		// New codes are only accepted from the effective date in the loaded hazard catalog
+		if hazard := catalog.Hazard(phenomena, significance); hazard != nil && hazard.Effective != nil && issued.Before(*hazard.Effective) {
+			return vtecs, errors.New("VTEC hazard " + hazard.Key() + " is not in effect yet")
+		}
 
 		// Get tracking number
 		etnString := segments[5]
@@ -225,3 +175,46 @@ func ParsePVTEC(text string, issued time.Time, ugc UGC) ([]PVTEC, error) {
 	return vtecs, nil
 
 }
+
+// NationalETN returns true if the ETN is assigned nationally (watches and tropical) rather than by the issuing office
+func (v PVTEC) NationalETN() bool {
+	return nationalETNs[v.Phenomena+"."+v.Significance] || tropicalPhenomena[v.Phenomena]
+}
+
+/*
+EventYear works out which year's ETN sequence the VTEC belongs to.
+ETNs are assigned when an event is issued so a NEW event belongs to the year it was issued in, even
+if it starts in the next one. Anything else is matched to a known prior event, first in the year it
+started in when the start is known and otherwise in the year it was issued and then the year before,
+so an event issued on Dec 31 and continued on Jan 1 keeps a single identity.
+lookup should return the end time of the prior event for a year, or nil if there isn't one.
+*/
+func (v PVTEC) EventYear(issued time.Time, lookup func(year int) (*time.Time, error)) (int, error) {
+	year := issued.UTC().Year()
+
+	if v.Action == "NEW" {
+		return year, nil
+	}
+
+	candidates := []int{year, year - 1}
+	if v.Start != nil && v.Start.Year() != year {
+		candidates = []int{v.Start.Year(), year}
+	}
+
+	for i, candidate := range candidates {
+		end, err := lookup(candidate)
+		if err != nil {
+			return 0, err
+		}
+		if end == nil {
+			continue
+		}
+		// Only continue the previous year's event if it could still be running
+		if candidate < year && i > 0 && end.Before(issued.Add(-VTECRolloverWindow)) {
+			continue
+		}
+		return candidate, nil
+	}
+
+	return year, nil
+}
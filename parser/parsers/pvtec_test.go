@@ -0,0 +1,118 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePVTECUntilFurtherNotice(t *testing.T) {
+	issued := testTime(t, "2024-01-01T03:00:00Z")
+	vtecs, err := ParsePVTEC("/O.CON.KDMX.WS.W.0004.000000T0000Z-000000T0000Z/", issued, UGC{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vtecs) != 1 {
+		t.Fatalf("got %d VTECs, want 1", len(vtecs))
+	}
+	if vtecs[0].Start != nil || vtecs[0].End != nil {
+		t.Errorf("000000T0000Z should leave the times unset, got %v and %v", vtecs[0].Start, vtecs[0].End)
+	}
+}
+
+func TestPVTECEventYear(t *testing.T) {
+	tests := []struct {
+		name   string
+		vtec   string
+		issued string
+		// The end of the stored event for each year
+		events map[int]string
+		want   int
+	}{
+		{
+			name:   "new event issued on Dec 31 that starts on Jan 1",
+			vtec:   "/O.NEW.KDMX.WS.W.0045.240101T0600Z-240102T0000Z/",
+			issued: "2023-12-31T21:00:00Z",
+			want:   2023,
+		},
+		{
+			name:   "new event in the new year reusing a low ETN",
+			vtec:   "/O.NEW.KDMX.WS.W.0001.240101T0600Z-240102T0000Z/",
+			issued: "2024-01-01T03:00:00Z",
+			events: map[int]string{2023: "2023-01-02T00:00:00Z"},
+			want:   2024,
+		},
+		{
+			name:   "continued into the new year with an unknown start",
+			vtec:   "/O.CON.KDMX.WS.W.0045.000000T0000Z-240102T0000Z/",
+			issued: "2024-01-01T09:00:00Z",
+			events: map[int]string{2023: "2024-01-02T00:00:00Z"},
+			want:   2023,
+		},
+		{
+			name:   "expired in the new year with an unknown start",
+			vtec:   "/O.EXP.KDMX.WS.W.0045.000000T0000Z-240102T0000Z/",
+			issued: "2024-01-02T00:05:00Z",
+			events: map[int]string{2023: "2024-01-02T00:00:00Z"},
+			want:   2023,
+		},
+		{
+			name:   "continued into the new year with the start in the old year",
+			vtec:   "/O.EXT.KDMX.WS.W.0045.231231T1800Z-240102T0600Z/",
+			issued: "2024-01-01T09:00:00Z",
+			events: map[int]string{2023: "2024-01-02T00:00:00Z", 2024: "2024-01-03T00:00:00Z"},
+			want:   2023,
+		},
+		{
+			name:   "started on Jan 1 but issued the year before",
+			vtec:   "/O.CON.KDMX.WS.W.0045.240101T0600Z-240102T0000Z/",
+			issued: "2024-01-01T03:00:00Z",
+			events: map[int]string{2023: "2024-01-02T00:00:00Z"},
+			want:   2023,
+		},
+		{
+			name:   "this year's event is found first",
+			vtec:   "/O.CON.KDMX.SV.W.0003.000000T0000Z-240105T2100Z/",
+			issued: "2024-01-05T20:30:00Z",
+			events: map[int]string{2023: "2023-12-31T23:00:00Z", 2024: "2024-01-05T21:00:00Z"},
+			want:   2024,
+		},
+		{
+			name:   "last year's event ended too long ago to continue",
+			vtec:   "/O.CON.KDMX.SV.W.0003.000000T0000Z-240120T2100Z/",
+			issued: "2024-01-20T20:30:00Z",
+			events: map[int]string{2023: "2023-01-03T00:00:00Z"},
+			want:   2024,
+		},
+		{
+			name:   "no event stored",
+			vtec:   "/O.CON.KDMX.SV.W.0003.000000T0000Z-240120T2100Z/",
+			issued: "2024-01-20T20:30:00Z",
+			want:   2024,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issued := testTime(t, test.issued)
+			vtecs, err := ParsePVTEC(test.vtec, issued, UGC{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			year, err := vtecs[0].EventYear(issued, func(year int) (*time.Time, error) {
+				end, ok := test.events[year]
+				if !ok {
+					return nil, nil
+				}
+				parsed := testTime(t, end)
+				return &parsed, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if year != test.want {
+				t.Errorf("got year %d, want %d", year, test.want)
+			}
+		})
+	}
+}
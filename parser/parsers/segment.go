@@ -0,0 +1,216 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The kinds of segment
+const (
+	SegmentTypeVTEC = "vtec" // A UGC block with VTEC
+	SegmentTypeUGC  = "ugc"  // A UGC block without VTEC, e.g. a zone forecast
+	SegmentTypeText = "text" // Neither, e.g. an area forecast discussion
+)
+
+// A piece of the product text. Start and End are byte offsets into the whole product
+type TextSpan struct {
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+/*
+Segment is a part of a product ended by $$. Every part of the segment that the parsers look for is kept
+as a span so it can be traced back to where it came from in the product.
+*/
+type Segment struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	TextSpan
+	UGC        *TextSpan  `json:"ugc,omitempty"`
+	VTEC       []TextSpan `json:"vtec,omitempty"`
+	HVTEC      []TextSpan `json:"hvtec,omitempty"`
+	Headlines  []TextSpan `json:"headlines,omitempty"` // The ...TEXT... lines
+	Paragraphs []TextSpan `json:"paragraphs,omitempty"`
+	Sections   []TextSpan `json:"sections,omitempty"` // Each part of the text closed by &&
+	LatLon     *TextSpan  `json:"latlon,omitempty"`
+	TML        *TextSpan  `json:"tml,omitempty"`
+	Tags       []TextSpan `json:"tags,omitempty"`
+	Signature  *TextSpan  `json:"signature,omitempty"` // What follows the last $$, usually the forecaster
+}
+
+var (
+	segmentEndRegexp      = regexp.MustCompile(`(?m:^[ \t]*\$\$.*$)`)
+	segmentUGCStartRegexp = regexp.MustCompile(`(?m:^[A-Z]{2}[CZ][A-Z0-9]{3}[->])`)
+	segmentUGCEndRegexp   = regexp.MustCompile(`[0-9]{6}-`)
+	segmentVTECRegexp     = regexp.MustCompile(`/[A-Z]\.[A-Z]+\.[A-Z]+\.[A-Z]+\.[A-Z]\.[0-9]+\.[0-9TZ]+-[0-9TZ]+/`)
+	segmentHVTECRegexp    = regexp.MustCompile(`/[A-Z0-9]{5}\.[0-3UN]\.[A-Z]{2}(?:\.[0-9TZ]+){3}\.(?:OO|NO|NR|UU)/`)
+	// Headlines can wrap over several lines but never over a blank one
+	segmentHeadlineRegexp = regexp.MustCompile(`(?m:^\.\.\.(?:[^\n]|\n[^\n])*?\.\.\.[ \t\r]*$)`)
+	segmentTagRegexp      = regexp.MustCompile(`(?m:^(?:TORNADO|TORNADO DAMAGE THREAT|THUNDERSTORM DAMAGE THREAT|HAIL THREAT|MAX HAIL SIZE|HAIL|WIND THREAT|MAX WIND GUST|WIND|WATERSPOUT|FLASH FLOOD|FLASH FLOOD DAMAGE THREAT|EXPECTED RAINFALL RATE|DAM FAILURE|SNOW SQUALL|SNOW SQUALL IMPACT)\.\.\.[^\n]*)`)
+	segmentSectionRegexp  = regexp.MustCompile(`(?m:^[ \t]*&&[ \t\r]*$)`)
+	segmentBlankRegexp    = regexp.MustCompile(`\n[ \t\r]*\n`)
+)
+
+// Make a span of text[start:end] without the whitespace around it. Base is where text starts in the product
+func newTextSpan(text string, base int, start int, end int) TextSpan {
+	value := text[start:end]
+	trimmed := strings.TrimLeft(value, " \t\r\n")
+	start += len(value) - len(trimmed)
+	trimmed = strings.TrimRight(trimmed, " \t\r\n")
+	return TextSpan{
+		Text:  trimmed,
+		Start: base + start,
+		End:   base + start + len(trimmed),
+	}
+}
+
+func (s TextSpan) overlaps(other TextSpan) bool {
+	return s.Start < other.End && other.Start < s.End
+}
+
+func findSpans(r *regexp.Regexp, text string, base int) []TextSpan {
+	spans := []TextSpan{}
+	for _, index := range r.FindAllStringIndex(text, -1) {
+		spans = append(spans, newTextSpan(text, base, index[0], index[1]))
+	}
+	return spans
+}
+
+func findSpan(r *regexp.Regexp, text string, base int) *TextSpan {
+	index := r.FindStringIndex(text)
+	if index == nil {
+		return nil
+	}
+	span := newTextSpan(text, base, index[0], index[1])
+	return &span
+}
+
+// The UGC block runs from the first code to the expiry time
+func findUGCSpan(text string, base int) *TextSpan {
+	start := segmentUGCStartRegexp.FindStringIndex(text)
+	if start == nil {
+		return nil
+	}
+	end := segmentUGCEndRegexp.FindStringIndex(text[start[0]:])
+	if end == nil {
+		return nil
+	}
+	span := newTextSpan(text, base, start[0], start[0]+end[1])
+	return &span
+}
+
+// Header is where the header of the product ends, nothing before it is a headline or paragraph
+func newSegment(id int, text string, base int, header int) Segment {
+	segment := Segment{
+		ID:       id,
+		TextSpan: newTextSpan(text, base, 0, len(text)),
+		UGC:      findUGCSpan(text, base),
+		VTEC:     findSpans(segmentVTECRegexp, text, base),
+		HVTEC:    findSpans(segmentHVTECRegexp, text, base),
+		LatLon:   findSpan(latlonRegexp, text, base),
+		TML:      findSpan(tmlRegexp, text, base),
+		Tags:     findSpans(segmentTagRegexp, text, base),
+	}
+
+	switch {
+	case segment.UGC != nil && len(segment.VTEC) > 0:
+		segment.Type = SegmentTypeVTEC
+	case segment.UGC != nil:
+		segment.Type = SegmentTypeUGC
+	default:
+		segment.Type = SegmentTypeText
+	}
+
+	segment.Headlines = []TextSpan{}
+	for _, headline := range findSpans(segmentHeadlineRegexp, text, base) {
+		// The headlines are not part of the header
+		if headline.Start >= header {
+			segment.Headlines = append(segment.Headlines, headline)
+		}
+	}
+
+	// The first section starts after the header
+	previous := 0
+	if header > base {
+		previous = min(header-base, len(text))
+	}
+	segment.Sections = []TextSpan{}
+	for _, index := range segmentSectionRegexp.FindAllStringIndex(text, -1) {
+		if section := newTextSpan(text, base, previous, index[0]); section.Text != "" {
+			segment.Sections = append(segment.Sections, section)
+		}
+		previous = index[1]
+	}
+
+	// Paragraphs are the blocks of text that are not one of the parts above
+	parts := append(append(append(append([]TextSpan{}, segment.VTEC...), segment.HVTEC...), segment.Headlines...), segment.Tags...)
+	for _, part := range []*TextSpan{segment.UGC, segment.LatLon, segment.TML} {
+		if part != nil {
+			parts = append(parts, *part)
+		}
+	}
+	segment.Paragraphs = []TextSpan{}
+	blocks := segmentBlankRegexp.FindAllStringIndex(text, -1)
+	previous = 0
+	for i := 0; i <= len(blocks); i++ {
+		end := len(text)
+		if i < len(blocks) {
+			end = blocks[i][0]
+		}
+		block := newTextSpan(text, base, previous, end)
+		if i < len(blocks) {
+			previous = blocks[i][1]
+		}
+
+		if block.Text == "" || block.Text == "&&" || block.End <= header {
+			continue
+		}
+		part := false
+		for _, p := range parts {
+			if block.overlaps(p) {
+				part = true
+				break
+			}
+		}
+		if !part {
+			segment.Paragraphs = append(segment.Paragraphs, block)
+		}
+	}
+
+	return segment
+}
+
+/*
+SegmentText splits product text on its $$ lines. Text with no $$ is a single segment and whatever follows the
+last $$ is kept as the signature of the last segment.
+*/
+func SegmentText(text string) []Segment {
+	header := 0
+	if h, err := ParseHeader(text); err == nil {
+		header = h.Body
+	}
+
+	ends := segmentEndRegexp.FindAllStringIndex(text, -1)
+	if len(ends) == 0 {
+		return []Segment{newSegment(0, text, 0, header)}
+	}
+
+	segments := []Segment{}
+	start := 0
+	for _, end := range ends {
+		segments = append(segments, newSegment(len(segments), text[start:end[0]], start, header))
+		start = end[1]
+	}
+
+	if signature := newTextSpan(text, 0, start, len(text)); signature.Text != "" {
+		segments[len(segments)-1].Signature = &signature
+	}
+
+	return segments
+}
+
+// Segments splits the product on its $$ lines
+func (p *Product) Segments() []Segment {
+	return SegmentText(p.Text)
+}
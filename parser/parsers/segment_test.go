@@ -0,0 +1,250 @@
+package parsers
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// Every span has to be the same text as the product at its offsets
+func checkSpan(t *testing.T, text string, name string, span TextSpan) {
+	t.Helper()
+	if span.Start < 0 || span.End > len(text) || span.Start > span.End {
+		t.Errorf("%s span %d-%d is outside the product", name, span.Start, span.End)
+		return
+	}
+	if text[span.Start:span.End] != span.Text {
+		t.Errorf("%s span %d-%d is %q, want %q", name, span.Start, span.End, text[span.Start:span.End], span.Text)
+	}
+}
+
+func checkSpans(t *testing.T, text string, segment Segment) {
+	t.Helper()
+	checkSpan(t, text, "segment", segment.TextSpan)
+	for name, spans := range map[string][]TextSpan{
+		"vtec":      segment.VTEC,
+		"hvtec":     segment.HVTEC,
+		"headline":  segment.Headlines,
+		"paragraph": segment.Paragraphs,
+		"section":   segment.Sections,
+		"tag":       segment.Tags,
+	} {
+		for _, span := range spans {
+			checkSpan(t, text, name, span)
+		}
+	}
+	for name, span := range map[string]*TextSpan{
+		"ugc":       segment.UGC,
+		"latlon":    segment.LatLon,
+		"tml":       segment.TML,
+		"signature": segment.Signature,
+	} {
+		if span != nil {
+			checkSpan(t, text, name, *span)
+		}
+	}
+}
+
+func spanTexts(spans []TextSpan) []string {
+	texts := []string{}
+	for _, span := range spans {
+		texts = append(texts, span.Text)
+	}
+	return texts
+}
+
+func optionalSpanText(span *TextSpan) string {
+	if span == nil {
+		return ""
+	}
+	return span.Text
+}
+
+func TestSegmentText(t *testing.T) {
+	type segmentWant struct {
+		kind       string
+		ugc        string
+		vtec       []string
+		hvtec      []string
+		headlines  []string // Only the start of each
+		paragraphs []string // Only the start of each
+		sections   int
+		latlon     string
+		tml        string
+		tags       []string
+		signature  string
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		text     string
+		segments []segmentWant
+	}{
+		{
+			name: "statement",
+			file: "SVSDMX.txt",
+			segments: []segmentWant{
+				{
+					kind:       SegmentTypeVTEC,
+					ugc:        "IAC015-169-142245-",
+					vtec:       []string{"/O.CAN.KDMX.SV.W.0101.000000T0000Z-240514T2245Z/"},
+					headlines:  []string{"...THE SEVERE THUNDERSTORM WARNING FOR BOONE AND STORY COUNTIES IS\nCANCELLED..."},
+					paragraphs: []string{"Severe Weather Statement", "Boone IA-Story IA-", "The storm which prompted"},
+					latlon:     "LAT...LON 4198 9370 4209 9352 4192 9341 4187 9367",
+					tml:        "TIME...MOT...LOC 2232Z 246DEG 29KT 4199 9357",
+					tags:       []string{},
+				},
+				{
+					kind:       SegmentTypeVTEC,
+					ugc:        "IAC079-083-142300-",
+					vtec:       []string{"/O.CON.KDMX.SV.W.0101.000000T0000Z-240514T2300Z/"},
+					headlines:  []string{"...A SEVERE THUNDERSTORM WARNING REMAINS IN EFFECT UNTIL 600 PM CDT\nFOR"},
+					paragraphs: []string{"Hamilton IA-Hardin IA-", "At 532 PM CDT", "HAZARD...60 mph"},
+					sections:   1,
+					latlon:     "LAT...LON 4237 9344 4239 9313 4248 9308 4250 9350\n      4244 9374",
+					tml:        "TIME...MOT...LOC 2232Z 246DEG 30KT 4231 9363 4218 9377\n      4209 9389",
+					tags:       []string{"HAIL THREAT...RADAR INDICATED", "MAX HAIL SIZE...1.00 IN", "WIND THREAT...RADAR INDICATED", "MAX WIND GUST...60 MPH"},
+					signature:  "JOHNSON",
+				},
+			},
+		},
+		{
+			name: "hydrologic",
+			file: "FLWDMX.txt",
+			segments: []segmentWant{
+				{
+					kind:       SegmentTypeVTEC,
+					ugc:        "IAC169-151530-",
+					vtec:       []string{"/O.NEW.KDMX.FL.W.0042.240514T1530Z-240516T1200Z/"},
+					hvtec:      []string{"/AMEI4.1.ER.240514T1800Z.240515T0600Z.240515T1800Z.NO/"},
+					headlines:  []string{"...The National Weather Service in Des Moines has issued a Flood\nWarning", "...FLOOD WARNING IN EFFECT UNTIL THURSDAY MORNING..."},
+					paragraphs: []string{"BULLETIN - IMMEDIATE BROADCAST REQUESTED", "Skunk River near Ames", "* WHAT...Minor flooding"},
+					sections:   1,
+					tags:       []string{},
+					signature:  "KOTENBERG",
+				},
+			},
+		},
+		{
+			name: "discussion",
+			text: "000\nFXUS63 KDMX 141130\nAFDDMX\n\nArea Forecast Discussion\n\n.KEY MESSAGES...\n\n- Storms this afternoon.\n\n&&\n\n.DISCUSSION...\nIssued at 630 AM CDT Tue May 14 2024\n\nA cold front moves through.\n\n&&\n\n.DMX WATCHES/WARNINGS/ADVISORIES...\nNone.\n&&\n\n$$\n\nSMITH\n",
+			segments: []segmentWant{
+				{
+					kind:       SegmentTypeText,
+					paragraphs: []string{"Area Forecast Discussion", ".KEY MESSAGES...", "- Storms this afternoon.", ".DISCUSSION...", "A cold front moves through.", ".DMX WATCHES/WARNINGS/ADVISORIES..."},
+					headlines:  []string{},
+					sections:   3,
+					tags:       []string{},
+					signature:  "SMITH",
+				},
+			},
+		},
+		{
+			name: "zone forecast",
+			text: "000\nFPUS53 KDMX 140800\nZFPDMX\n\nIAZ048-142100-\nBoone-\n300 AM CDT Tue May 14 2024\n\n.TODAY...Sunny. Highs in the upper 70s.\n",
+			segments: []segmentWant{
+				{
+					kind:       SegmentTypeUGC,
+					ugc:        "IAZ048-142100-",
+					paragraphs: []string{".TODAY...Sunny."},
+					headlines:  []string{},
+					tags:       []string{},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text := test.text
+			if test.file != "" {
+				b, err := os.ReadFile("testdata/" + test.file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				text = string(b)
+			}
+			product := Product{Text: text}
+			segments := product.Segments()
+			if len(segments) != len(test.segments) {
+				t.Fatalf("got %d segments, want %d", len(segments), len(test.segments))
+			}
+
+			for i, want := range test.segments {
+				segment := segments[i]
+				checkSpans(t, text, segment)
+
+				if segment.ID != i || segment.Type != want.kind {
+					t.Errorf("segment %d: got %d %s, want %s", i, segment.ID, segment.Type, want.kind)
+				}
+				if got := optionalSpanText(segment.UGC); got != want.ugc {
+					t.Errorf("segment %d: got UGC %q, want %q", i, got, want.ugc)
+				}
+				if got := optionalSpanText(segment.LatLon); got != want.latlon {
+					t.Errorf("segment %d: got LAT...LON %q, want %q", i, got, want.latlon)
+				}
+				if got := optionalSpanText(segment.TML); got != want.tml {
+					t.Errorf("segment %d: got TIME...MOT...LOC %q, want %q", i, got, want.tml)
+				}
+				if got := optionalSpanText(segment.Signature); got != want.signature {
+					t.Errorf("segment %d: got signature %q, want %q", i, got, want.signature)
+				}
+				if len(segment.Sections) != want.sections {
+					t.Errorf("segment %d: got %d sections, want %d", i, len(segment.Sections), want.sections)
+				}
+				for name, check := range map[string]struct {
+					got    []TextSpan
+					want   []string
+					prefix bool
+				}{
+					"VTEC":      {segment.VTEC, want.vtec, false},
+					"H-VTEC":    {segment.HVTEC, want.hvtec, false},
+					"tags":      {segment.Tags, want.tags, false},
+					"headlines": {segment.Headlines, want.headlines, true},
+					"paragraph": {segment.Paragraphs, want.paragraphs, true},
+				} {
+					got := spanTexts(check.got)
+					if len(got) != len(check.want) {
+						t.Errorf("segment %d: got %s %q, want %q", i, name, got, check.want)
+						continue
+					}
+					for j := range got {
+						if got[j] != check.want[j] && !(check.prefix && strings.HasPrefix(got[j], check.want[j])) {
+							t.Errorf("segment %d: got %s %q, want %q", i, name, got[j], check.want[j])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSegmentSectionsStartAfterHeader(t *testing.T) {
+	for _, file := range []string{"FLWDMX.txt", "SVSDMX.txt"} {
+		b, err := os.ReadFile("testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(b)
+		header, err := ParseHeader(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The first section starts after the header, those of later segments at the segment
+		segments := SegmentText(text)
+		for i, segment := range segments {
+			if len(segment.Sections) == 0 {
+				continue
+			}
+			section := segment.Sections[0]
+			if section.Start < header.Body || section.Start < segment.Start {
+				t.Errorf("%s segment %d: got the first section at %d, want it after the header at %d and segment at %d", file, i, section.Start, header.Body, segment.Start)
+			}
+			if strings.TrimSpace(text[max(header.Body, segment.Start):section.Start]) != "" {
+				t.Errorf("%s segment %d: got the first section at %d %q", file, i, section.Start, section.Text)
+			}
+		}
+	}
+}
@@ -1,14 +1,43 @@
 package parsers
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Marks a break between two contours that share a category line
+const ptsLineBreak = "99999"
+
+// The kinds of SPC outlook that share the points format
+const (
+	OutlookConvective  = "convective"
+	OutlookFireWeather = "fire"
+)
+
+// The outlook kind for each AWIPS product
+var outlookTypes = map[string]string{
+	"PTS": OutlookConvective,
+	"PFW": OutlookFireWeather,
+}
+
+/*
+The categories of each outlook from lowest to highest. Every area of a category sits inside the area of the one
+below it. Probability thresholds are nested by their value and anything else, such as SIGN, stands on its own.
+*/
+var ptsCategoryOrders = [][]string{
+	{"TSTM", "MRGL", "SLGT", "ENH", "MDT", "HIGH"},
+	{"ELEV", "CRIT", "EXTM"},
+	{"IDRT", "SDRT"},
+}
+
 type PTSCategories struct {
 	Category string               `json:"category"`
+	Lines    [][][2]float64       `json:"-"` // The contours as they were issued
 	Features *MultiPolygonFeature `json:"features"`
 }
 
@@ -20,93 +49,309 @@ type PTSSegments struct {
 type PTS struct {
 	ID       string         `json:"id"`
 	Original string         `json:"original"`
-	Day      int            `json:"dat"`
+	Type     string         `json:"type"`
+	Product  string         `json:"product"`
+	Day      int            `json:"day"`
 	Issued   time.Time      `json:"issued"`
+	Start    time.Time      `json:"start"`
 	Expires  time.Time      `json:"expires"`
 	Segments *[]PTSSegments `json:"segments"`
 }
 
-func ParsePTSProduct(product *Product) (*PTS, error) {
-
-	switch product.AWIPS.WFO {
-	case "DY1":
-		parseDY1(product.Text)
-		return nil, nil
-		// case "DY2":
-
-		// case "DY3":
+/*
+Resolve a DDHHMM time to the month closest to ref, since the SPC products only give the day of the month
+*/
+func ddhhmmNear(ddhhmm string, ref time.Time) (time.Time, error) {
+	t, err := time.Parse("021504", ddhhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
 
-		// case "D48":
+	best := time.Time{}
+	for _, offset := range []int{-1, 0, 1} {
+		candidate := time.Date(ref.Year(), ref.Month()+time.Month(offset), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+		// Days that don't exist in the month roll over so skip them
+		if candidate.Day() != t.Day() {
+			continue
+		}
+		if best.IsZero() || candidate.Sub(ref).Abs() < best.Sub(ref).Abs() {
+			best = candidate
+		}
+	}
+	if best.IsZero() {
+		return best, errors.New("could not resolve day " + ddhhmm)
 	}
 
-	return nil, nil
+	return best, nil
+}
 
+type PTSMatch struct {
+	Type     string `json:"type"`
+	Category string `json:"category"`
 }
 
-func parseSegment(segment string, name string) (*[]PTSSegments, error) {
-	categoryRegexp := regexp.MustCompile(`(0\.[0-9]{2}|[A-Z]{3,4})( {3,7}[0-9 ]+\n)+`)
-	categorySegments := categoryRegexp.FindAllString(segment, -1)
+// CategoriesAt returns every category in the outlook that covers the point
+func (o *PTS) CategoriesAt(point [2]float64) []PTSMatch {
+	matches := []PTSMatch{}
+	if o.Segments == nil {
+		return matches
+	}
+	for _, segment := range *o.Segments {
+		if segment.Categories == nil {
+			continue
+		}
+		for _, category := range *segment.Categories {
+			if PointInMultiPolygon(point, category.Features) {
+				matches = append(matches, PTSMatch{
+					Type:     segment.Type,
+					Category: category.Category,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+/*
+ParsePTSProduct parses the SPC outlook points products. This covers the convective outlooks
+(PTSDY1, PTSDY2, PTSDY3, PTSD48) and the fire weather outlooks (PFWFD1, PFWFD2, PFWF38).
+*/
+func ParsePTSProduct(product *Product) ([]PTS, error) {
+	text := product.Text
 
-	categories := []*PTSCategories{}
-	for _, s := range categorySegments {
-		s = strings.TrimSpace(s)
+	outlookType, ok := outlookTypes[product.AWIPS.Product]
+	if !ok {
+		return nil, errors.New("product " + product.AWIPS.Original + " is not an SPC outlook")
+	}
 
-		spaceRegexp := regexp.MustCompile(`[ \n]{2,}`)
-		pointTextArr := strings.Split(spaceRegexp.ReplaceAllString(s, " "), " ")
+	segments := product.Segments()
 
-		title := pointTextArr[0]
-		category := &PTSCategories{
-			Category: title,
-			Features: &MultiPolygonFeature{
-				Type:        "MultiPolygon",
-				Coordinates: [][][][2]float64{{{}}},
-			},
+	validRegexp := regexp.MustCompile(`VALID TIME ([0-9]{6})Z - ([0-9]{6})Z`)
+	var valid []string
+	for _, segment := range segments {
+		if valid = validRegexp.FindStringSubmatch(segment.Text); valid != nil {
+			break
 		}
+	}
+	if valid == nil {
+		return nil, errors.New("failed to find PTS valid time")
+	}
+
+	start, err := ddhhmmNear(valid[1], product.Issued)
+	if err != nil {
+		return nil, errors.New("failed to parse PTS valid start time")
+	}
+	end, err := ddhhmmNear(valid[2], start)
+	if err != nil {
+		return nil, errors.New("failed to parse PTS valid end time")
+	}
+
+	// Each day is introduced by one or more "... OUTLOOK POINTS DAY N" lines and each of its outlooks is closed by &&
+	dayRegexp := regexp.MustCompile(`(?m:^[A-Z ]*OUTLOOK POINTS DAY ([0-9]))`)
+	days := []int{}
+	daySegments := map[int][]PTSSegments{}
+	day := 0
+	for _, segment := range segments {
+		for _, section := range segment.Sections {
+			if match := dayRegexp.FindStringSubmatch(section.Text); match != nil {
+				day, err = strconv.Atoi(match[1])
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := daySegments[day]; !ok {
+					days = append(days, day)
+					daySegments[day] = []PTSSegments{}
+				}
+			}
+			if day == 0 {
+				continue
+			}
 
-		found := false
-		for _, i := range categories {
-			if i.Category == title && !found {
-				found = true
+			parsed, err := parsePTSSection(text, segment, section)
+			if err != nil {
+				return nil, fmt.Errorf("day %d: %s", day, err.Error())
 			}
+			daySegments[day] = append(daySegments[day], parsed...)
 		}
+	}
+	if len(days) == 0 {
+		return nil, errors.New("failed to find any outlook days in PTS")
+	}
 
-		if !found {
-			categories = append(categories, category)
+	outlooks := []PTS{}
+	for _, day := range days {
+		segments := daySegments[day]
+
+		// Days 4-8 (and 3-8 for fire weather) share one valid time so split it into each day
+		dayStart := start
+		dayEnd := end
+		if len(days) > 1 {
+			dayStart = start.Add(time.Duration(day-days[0]) * 24 * time.Hour)
+			dayEnd = dayStart.Add(24 * time.Hour)
 		}
 
-		fmt.Println(found)
+		outlooks = append(outlooks, PTS{
+			ID:       product.AWIPS.Product + strconv.Itoa(day) + product.Issued.Format("200601021504"),
+			Original: text,
+			Type:     outlookType,
+			Product:  product.AWIPS.Product + product.AWIPS.WFO,
+			Day:      day,
+			Issued:   product.Issued,
+			Start:    dayStart,
+			Expires:  dayEnd,
+			Segments: &segments,
+		})
+	}
 
-		items := pointTextArr[1:]
+	return outlooks, nil
+}
 
-		points := [][2]float64{}
-		fmt.Println(category.Features.Coordinates[0][0])
-		for _, i := range items {
-			point, err := ParsePoint([]string{i})
-			if err != nil {
-				return nil, err
-			}
-			asd := *point
-			points = append(points, asd)
+// Parse each "... NAME ..." headline of a section closed by && up to the next one
+func parsePTSSection(text string, segment Segment, section TextSpan) ([]PTSSegments, error) {
+	nameRegexp := regexp.MustCompile(`^\.\.\. ([A-Z ]+?) \.\.\.$`)
+
+	headlines := []TextSpan{}
+	names := []string{}
+	for _, headline := range segment.Headlines {
+		if headline.Start < section.Start || headline.End > section.End {
+			continue
+		}
+		if match := nameRegexp.FindStringSubmatch(headline.Text); match != nil {
+			headlines = append(headlines, headline)
+			names = append(names, match[1])
+		}
+	}
+
+	segments := []PTSSegments{}
+	for i, headline := range headlines {
+		stop := section.End
+		if i+1 < len(headlines) {
+			stop = headlines[i+1].Start
 		}
 
-		// category.Features.Coordinates[0][0] = append(category.Features.Coordinates[0][0], points)
-		fmt.Println(category.Features.Coordinates[0][0])
+		categories, err := parsePTSSegment(text[headline.End:stop])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", names[i], err.Error())
+		}
 
+		segments = append(segments, PTSSegments{
+			Type:       names[i],
+			Categories: categories,
+		})
 	}
 
-	return nil, nil
+	return segments, nil
 }
 
-func parseDY1(text string) {
+/*
+Parse the categories of a section. A category starts at the beginning of a line with its label
+(TSTM, SLGT, 0.05, SIGN...) and carries on over the indented lines below it. 99999 ends the current
+contour and starts another in the same category.
+*/
+func parsePTSSegment(segment string) (*[]PTSCategories, error) {
+	categories := []PTSCategories{}
+	current := -1
 
-	segmentEndRegexp := regexp.MustCompile("&&")
+	for _, line := range strings.Split(segment, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 
-	tornadoRegexp := regexp.MustCompile(`\.\.\. TORNADO \.\.\.`)
-	startIndex := tornadoRegexp.FindStringIndex(text)[1]
+		fields := strings.Fields(line)
+		if line[0] != ' ' {
+			label := fields[0]
+			fields = fields[1:]
 
-	endIndex := segmentEndRegexp.FindStringIndex(text)[0]
-	segment := strings.TrimSpace(text[startIndex:endIndex])
+			current = -1
+			for i, c := range categories {
+				if c.Category == label {
+					current = i
+				}
+			}
+			if current < 0 {
+				categories = append(categories, PTSCategories{
+					Category: label,
+					Lines:    [][][2]float64{},
+				})
+				current = len(categories) - 1
+			}
+			categories[current].Lines = append(categories[current].Lines, [][2]float64{})
+		}
 
-	parseSegment(segment, "TORNADO")
+		if current < 0 {
+			return nil, errors.New("found points before a category")
+		}
 
+		category := &categories[current]
+		for _, field := range fields {
+			if field == ptsLineBreak {
+				category.Lines = append(category.Lines, [][2]float64{})
+				continue
+			}
+			if len(field) != 8 {
+				return nil, errors.New("invalid point " + field + " in category " + category.Category)
+			}
+			point, err := ParsePoint([]string{field})
+			if err != nil {
+				return nil, err
+			}
+			last := len(category.Lines) - 1
+			category.Lines[last] = append(category.Lines[last], *point)
+		}
+	}
+
+	for i := range categories {
+		lines := [][][2]float64{}
+		for _, l := range categories[i].Lines {
+			if len(l) > 1 {
+				lines = append(lines, l)
+			}
+		}
+		categories[i].Lines = lines
+		categories[i].Features = PTSLinesToPolygons(lines, CONUS)
+	}
+
+	nestPTSCategories(categories)
+
+	return &categories, nil
+}
+
+/*
+Each contour is closed on its own so a higher category can spill past the one below it where the lines were
+drawn loosely. Clip every category to the next lowest one that was issued so the areas nest.
+*/
+func nestPTSCategories(categories []PTSCategories) {
+	chains := [][]int{}
+	for _, order := range ptsCategoryOrders {
+		chain := []int{}
+		for _, label := range order {
+			for i := range categories {
+				if categories[i].Category == label {
+					chain = append(chain, i)
+				}
+			}
+		}
+		chains = append(chains, chain)
+	}
+
+	probabilities := []int{}
+	for i := range categories {
+		if _, err := strconv.ParseFloat(categories[i].Category, 64); err == nil {
+			probabilities = append(probabilities, i)
+		}
+	}
+	sort.SliceStable(probabilities, func(a, b int) bool {
+		x, _ := strconv.ParseFloat(categories[probabilities[a]].Category, 64)
+		y, _ := strconv.ParseFloat(categories[probabilities[b]].Category, 64)
+		return x < y
+	})
+	chains = append(chains, probabilities)
+
+	for _, chain := range chains {
+		for i := 1; i < len(chain); i++ {
+			inner := &categories[chain[i]]
+			inner.Features = IntersectMultiPolygons(inner.Features, categories[chain[i-1]].Features)
+		}
+	}
 }
@@ -2,6 +2,7 @@ package parsers
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,60 +14,76 @@ type TML struct {
 	Time      time.Time  `json:"time"`
 	Direction int        `json:"direction"`
 	Speed     int        `json:"speed"`
-	Location  [2]float64 `json:"location"`
+	Location  [2]float64 `json:"location"` // The first point
+	// Every point given. More than one is a line of storms
+	Points [][2]float64       `json:"points"`
+	Line   *LineStringFeature `json:"line,omitempty"`
 }
 
-func ParseTML(text string, issued time.Time) (*TML, error) {
-	tmlRegexp := regexp.MustCompile(`(?m:^(TIME\.\.\.MOT\.\.\.LOC)([A-Za-z0-9 ]*))`)
-	original := tmlRegexp.FindString(text)
+// Like LAT...LON, the points can wrap on to lines that start with spaces
+var tmlRegexp = regexp.MustCompile(`(?m:^TIME\.\.\.MOT\.\.\.LOC([^\n]*(?:\n[ \t]+[0-9][0-9 \t\r]*)*))`)
 
-	if original == "" {
+func ParseTML(text string, issued time.Time) (*TML, error) {
+	match := tmlRegexp.FindStringSubmatch(text)
+	if match == nil {
 		return nil, nil
 	}
+	original := strings.TrimRight(match[0], " \t\r\n")
 
-	segments := strings.Split(original, " ")[1:]
-
-	parsedTime, err := time.Parse(("1504Z"), segments[0])
-
-	if err != nil {
-		return nil, errors.New("could not parse TML time")
+	fields := strings.Fields(match[1])
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("TML %q is too short, expected a time, motion and location", original)
 	}
 
-	time := time.Date(issued.Year(), issued.Month(), issued.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, time.Now().UTC().Location())
-
-	direction, err := strconv.Atoi(segments[1][:3])
-
+	parsedTime, err := time.Parse("1504Z", fields[0])
 	if err != nil {
-		return nil, errors.New("could not parse direction in TML")
+		return nil, fmt.Errorf("could not parse TML time %q", fields[0])
 	}
 
-	speed, err := strconv.Atoi(segments[2][:2])
+	// The time has no date so take the one closest to the issued time, as a TML just after 00Z can be from the day before
+	issued = issued.UTC()
+	t := time.Date(issued.Year(), issued.Month(), issued.Day(), parsedTime.Hour(), parsedTime.Minute(), 0, 0, time.UTC)
+	if t.Sub(issued) > 12*time.Hour {
+		t = t.AddDate(0, 0, -1)
+	} else if issued.Sub(t) > 12*time.Hour {
+		t = t.AddDate(0, 0, 1)
+	}
 
-	if err != nil {
-		return nil, errors.New("could not parse speed in TML")
+	if !strings.HasSuffix(fields[1], "DEG") {
+		return nil, fmt.Errorf("TML direction %q does not end in DEG", fields[1])
+	}
+	direction, err := strconv.Atoi(strings.TrimSuffix(fields[1], "DEG"))
+	if err != nil || direction < 0 || direction > 360 {
+		return nil, fmt.Errorf("could not parse direction %q in TML", fields[1])
 	}
 
-	latInit, err := strconv.Atoi(segments[3])
-	if err != nil {
-		return nil, errors.New("failed to parse LAT...LON lat")
+	if !strings.HasSuffix(fields[2], "KT") {
+		return nil, fmt.Errorf("TML speed %q does not end in KT", fields[2])
 	}
-	lonInit, err := strconv.Atoi(segments[4])
-	if err != nil {
-		return nil, errors.New("failed to parse LAT...LON lon")
+	speed, err := strconv.Atoi(strings.TrimSuffix(fields[2], "KT"))
+	if err != nil || speed < 0 {
+		return nil, fmt.Errorf("could not parse speed %q in TML", fields[2])
 	}
 
-	lat := float64(latInit) / 100
-	lon := (float64(lonInit) / 100) * -1
-	if lon <= -180.0 {
-		lon = lon + 360.0
+	points, err := parsePoints(fields[3:])
+	if err != nil {
+		return nil, errors.New("TML: " + err.Error())
 	}
-	location := [2]float64{lon, lat}
 
-	return &TML{
+	tml := TML{
 		Original:  original,
-		Time:      time,
+		Time:      t,
 		Direction: direction,
 		Speed:     speed,
-		Location:  location,
-	}, nil
+		Location:  points[0],
+		Points:    points,
+	}
+	if len(points) > 1 {
+		tml.Line = &LineStringFeature{
+			Type:        "LineString",
+			Coordinates: points,
+		}
+	}
+
+	return &tml, nil
 }
@@ -0,0 +1,124 @@
+package parsers
+
+import (
+	"testing"
+)
+
+func TestParseTML(t *testing.T) {
+	issued := testTime(t, "2024-05-14T22:32:00Z")
+
+	tests := []struct {
+		name      string
+		text      string
+		issued    string // When it differs from the shared issued time
+		time      string
+		direction int
+		speed     int
+		points    [][2]float64 // Nil when there is no TML
+		line      bool
+		err       bool
+	}{
+		{
+			name:      "one point",
+			text:      "TIME...MOT...LOC 2232Z 246DEG 29KT 4199 9357\n",
+			time:      "2024-05-14T22:32:00Z",
+			direction: 246,
+			speed:     29,
+			points:    [][2]float64{{-93.57, 41.99}},
+		},
+		{
+			name:      "line of storms",
+			text:      "TIME...MOT...LOC 2232Z 246DEG 30KT 4231 9363 4218 9377\n      4209 9389\n\nHAIL THREAT...RADAR INDICATED\n",
+			time:      "2024-05-14T22:32:00Z",
+			direction: 246,
+			speed:     30,
+			points:    [][2]float64{{-93.63, 42.31}, {-93.77, 42.18}, {-93.89, 42.09}},
+			line:      true,
+		},
+		{
+			name:      "Guam",
+			text:      "TIME...MOT...LOC 2232Z 090DEG 5KT 1340 21520\n",
+			time:      "2024-05-14T22:32:00Z",
+			direction: 90,
+			speed:     5,
+			points:    [][2]float64{{144.8, 13.4}},
+		},
+		{
+			// Issued just after 00Z about a storm from just before
+			name:      "day before",
+			text:      "TIME...MOT...LOC 2355Z 246DEG 29KT 4199 9357\n",
+			issued:    "2024-05-15T00:05:00Z",
+			time:      "2024-05-14T23:55:00Z",
+			direction: 246,
+			speed:     29,
+			points:    [][2]float64{{-93.57, 41.99}},
+		},
+		{
+			name:      "day after",
+			text:      "TIME...MOT...LOC 0005Z 246DEG 29KT 4199 9357\n",
+			issued:    "2024-05-14T23:55:00Z",
+			time:      "2024-05-15T00:05:00Z",
+			direction: 246,
+			speed:     29,
+			points:    [][2]float64{{-93.57, 41.99}},
+		},
+		{
+			name:      "stationary",
+			text:      "TIME...MOT...LOC 2232Z 000DEG 0KT 4199 9357\n",
+			time:      "2024-05-14T22:32:00Z",
+			direction: 0,
+			speed:     0,
+			points:    [][2]float64{{-93.57, 41.99}},
+		},
+		{name: "none", text: "LAT...LON 4198 9370 4209 9352 4192 9341\n"},
+		{name: "no location", text: "TIME...MOT...LOC 2232Z 246DEG 29KT\n", err: true},
+		{name: "bad time", text: "TIME...MOT...LOC 2572Z 246DEG 29KT 4199 9357\n", err: true},
+		{name: "no DEG", text: "TIME...MOT...LOC 2232Z 246 29KT 4199 9357\n", err: true},
+		{name: "direction past 360", text: "TIME...MOT...LOC 2232Z 400DEG 29KT 4199 9357\n", err: true},
+		{name: "no KT", text: "TIME...MOT...LOC 2232Z 246DEG 29 4199 9357\n", err: true},
+		{name: "bad speed", text: "TIME...MOT...LOC 2232Z 246DEG -5KT 4199 9357\n", err: true},
+		{name: "odd values", text: "TIME...MOT...LOC 2232Z 246DEG 29KT 4199 9357 4209\n", err: true},
+		{name: "bad point", text: "TIME...MOT...LOC 2232Z 246DEG 29KT 41 9357\n", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at := issued
+			if test.issued != "" {
+				at = testTime(t, test.issued)
+			}
+			tml, err := ParseTML(test.text, at)
+			if test.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", tml)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.points == nil {
+				if tml != nil {
+					t.Errorf("got %v, want no TML", tml)
+				}
+				return
+			}
+			if tml == nil {
+				t.Fatal("found no TML")
+			}
+			if !tml.Time.Equal(testTime(t, test.time)) || tml.Direction != test.direction || tml.Speed != test.speed {
+				t.Errorf("got %s %d %d, want %s %d %d", tml.Time, tml.Direction, tml.Speed, test.time, test.direction, test.speed)
+			}
+			if !closePoints(tml.Points, test.points) || !closePoints([][2]float64{tml.Location}, test.points[:1]) {
+				t.Errorf("got %v at %v, want %v", tml.Points, tml.Location, test.points)
+			}
+			if test.line {
+				if tml.Line == nil || tml.Line.Type != "LineString" || !closePoints(tml.Line.Coordinates, test.points) {
+					t.Errorf("got line %v, want %v", tml.Line, test.points)
+				}
+			} else if tml.Line != nil {
+				t.Errorf("got line %v for a single point", tml.Line)
+			}
+		})
+	}
+}
@@ -10,6 +10,41 @@ import (
 	"github.com/TheRangiCrew/NWWS-GO/parser/util"
 )
 
+// The classes of UGC zone
+const (
+	UGCCounty     = "county"
+	UGCPublic     = "public"
+	UGCFire       = "fire"
+	UGCCoastal    = "coastal"
+	UGCOffshore   = "offshore"
+	UGCHighSeas   = "highseas"
+	UGCGreatLakes = "greatlakes"
+	// A marine zone that can't be narrowed down to coastal, offshore or high seas without reference data
+	UGCMarine = "marine"
+)
+
+// Marine "states" used by the coastal, offshore and high seas zones
+var marineUGCPrefixes = map[string]bool{
+	"AM": true, // Western Atlantic and Caribbean
+	"AN": true, // Western North Atlantic
+	"GM": true, // Gulf of Mexico
+	"PH": true, // Hawaiian waters
+	"PK": true, // Alaskan waters
+	"PM": true, // Marianas waters
+	"PS": true, // American Samoa waters
+	"PZ": true, // Eastern North Pacific
+}
+
+var greatLakesUGCPrefixes = map[string]bool{
+	"LC": true, // Lake St. Clair
+	"LE": true, // Lake Erie
+	"LH": true, // Lake Huron
+	"LM": true, // Lake Michigan
+	"LO": true, // Lake Ontario
+	"LS": true, // Lake Superior
+	"SL": true, // St. Lawrence River
+}
+
 type UGC struct {
 	Original string    `json:"original"`
 	States   []State   `json:"states"`
@@ -19,9 +54,49 @@ type UGC struct {
 type State struct {
 	Name  string   `json:"name"`
 	Type  string   `json:"type"`
+	Class string   `json:"class"`
 	Zones []string `json:"zones"`
 }
 
+type UGCCode struct {
+	Code  string `json:"code"`
+	Class string `json:"class"`
+}
+
+// Get the class of a state block from its prefix and type alone
+func ugcClass(name string, t string) string {
+	if t == "C" {
+		return UGCCounty
+	}
+	if greatLakesUGCPrefixes[name] {
+		return UGCGreatLakes
+	}
+	if marineUGCPrefixes[name] {
+		return UGCMarine
+	}
+	return UGCPublic
+}
+
+/*
+ClassifyUGC narrows the class of a zone down using the reference data, if it has been loaded.
+Marine zones become coastal, offshore or high seas and unknown zones keep the class of their state block.
+*/
+func ClassifyUGC(code string, class string) string {
+	ref := GetUGCReference()
+	if ref == nil || class == UGCCounty || class == UGCFire || class == UGCGreatLakes {
+		return class
+	}
+	zone, ok := ref.Lookup(code, false)
+	if !ok {
+		return class
+	}
+	switch zone.Type {
+	case UGCCoastal, UGCOffshore, UGCHighSeas, UGCPublic:
+		return zone.Type
+	}
+	return class
+}
+
 func ParseUGC(text string, issued time.Time) (*UGC, error) {
 	ugcStartRegex := regexp.MustCompile("(?m:^[A-Z]{2}(C|Z)[A-Z0-9]{3}(-|>))")
 	startIndex := ugcStartRegex.FindStringIndex(text)
@@ -38,7 +113,8 @@ func ParseUGC(text string, issued time.Time) (*UGC, error) {
 	// Subtract 1 to remove the - at the end of the UGC
 	original := start[:endIndex[1]-1]
 
-	original = strings.ReplaceAll(original, "\n", "")
+	// UGC lines wrap so remove the line breaks and any stray spacing
+	original = strings.NewReplacer("\n", "", "\r", "", " ", "").Replace(original)
 
 	segments := strings.Split(original, "-")
 
@@ -64,36 +140,60 @@ func ParseUGC(text string, issued time.Time) (*UGC, error) {
 
 	states := []State{}
 	currentState := -1
-	alphabetRegexp := regexp.MustCompile("[A-Z]")
-	bracketRegexp := regexp.MustCompile(">")
+	stateRegexp := regexp.MustCompile("^[A-Z]{2}[CZ]")
+	rangeRegexp := regexp.MustCompile("^([A-Z0-9]{3})>([A-Z0-9]{3})$")
+	zoneRegexp := regexp.MustCompile("^[A-Z0-9]{3}$")
 
 	for _, s := range segments {
+		if s == "" {
+			continue
+		}
 
-		if alphabetRegexp.MatchString(s) {
+		// A new state (or a new type in the same state) starts with its prefix, e.g. ANZ or INC
+		if stateRegexp.MatchString(s) {
+			name := s[0:2]
+			t := s[2:3]
 			currentState++
 			states = append(states, State{
-				Name:  s[0:2],
-				Type:  s[2:3],
+				Name:  name,
+				Type:  t,
+				Class: ugcClass(name, t),
 				Zones: []string{},
 			})
 			s = s[3:]
 		}
 
-		if bracketRegexp.MatchString(s) {
-			start, err := strconv.Atoi(s[:3])
+		if currentState < 0 {
+			return nil, errors.New("UGC zone " + s + " has no state")
+		}
+
+		if strings.Contains(s, ">") {
+			match := rangeRegexp.FindStringSubmatch(s)
+			if match == nil {
+				return nil, errors.New("could not parse UGC range " + s)
+			}
+
+			start, err := strconv.Atoi(match[1])
 			if err != nil {
 				return nil, errors.New("could not parse UGC int")
 			}
 
-			end, err := strconv.Atoi(s[4:])
+			end, err := strconv.Atoi(match[2])
 			if err != nil {
 				return nil, errors.New("could not parse UGC int")
 			}
 
+			if end < start {
+				return nil, errors.New("UGC range " + s + " ends before it starts")
+			}
+
 			for i := start; i <= end; i++ {
 				states[currentState].Zones = append(states[currentState].Zones, util.PadZero(strconv.Itoa(i), 3))
 			}
 		} else {
+			if !zoneRegexp.MatchString(s) {
+				return nil, errors.New("could not parse UGC zone " + s)
+			}
 			states[currentState].Zones = append(states[currentState].Zones, s)
 		}
 	}
@@ -104,3 +204,53 @@ func ParseUGC(text string, issued time.Time) (*UGC, error) {
 		Expires:  expires,
 	}, nil
 }
+
+// Codes returns every zone in the UGC as a full code, e.g. ALZ001
+func (u UGC) Codes() []string {
+	codes := []string{}
+	for _, s := range u.States {
+		for _, z := range s.Zones {
+			codes = append(codes, s.Name+s.Type+z)
+		}
+	}
+	return codes
+}
+
+// Zones returns every zone in the UGC with its class
+func (u UGC) Zones() []UGCCode {
+	codes := []UGCCode{}
+	for _, s := range u.States {
+		for _, z := range s.Zones {
+			code := s.Name + s.Type + z
+			codes = append(codes, UGCCode{
+				Code:  code,
+				Class: ClassifyUGC(code, s.Class),
+			})
+		}
+	}
+	return codes
+}
+
+/*
+MarkFire marks the public zones in the UGC as fire weather zones. Fire zones share their codes with the
+public zones so this can only be known from the product, such as a Red Flag Warning.
+*/
+func (u *UGC) MarkFire() {
+	for i := range u.States {
+		if u.States[i].Class == UGCPublic {
+			u.States[i].Class = UGCFire
+		}
+	}
+}
+
+// Mixed returns true if the UGC covers more than one class of zone
+func (u UGC) Mixed() bool {
+	class := ""
+	for _, s := range u.States {
+		if class != "" && s.Class != class {
+			return true
+		}
+		class = s.Class
+	}
+	return false
+}
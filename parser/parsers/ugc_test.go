@@ -0,0 +1,164 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUGCClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		t     string
+		class string
+	}{
+		{"IA", "C", UGCCounty},
+		{"IA", "Z", UGCPublic},
+		{"LM", "Z", UGCGreatLakes},
+		{"SL", "Z", UGCGreatLakes},
+		{"AN", "Z", UGCMarine},
+		{"GM", "Z", UGCMarine},
+		{"PZ", "Z", UGCMarine},
+		{"PM", "Z", UGCMarine},
+	}
+	for _, test := range tests {
+		if class := ugcClass(test.name, test.t); class != test.class {
+			t.Errorf("%s%s is %s, want %s", test.name, test.t, class, test.class)
+		}
+	}
+}
+
+func TestParseUGCRanges(t *testing.T) {
+	issued := testTime(t, "2024-05-21T20:00:00Z")
+
+	tests := []struct {
+		text  string
+		codes string
+		err   bool
+	}{
+		{"IAZ004>006-212100-", "IAZ004,IAZ005,IAZ006", false},
+		{"IAZ004>004-212100-", "IAZ004", false},
+		{"IAZ004>006-010-\n015>016-212100-", "IAZ004,IAZ005,IAZ006,IAZ010,IAZ015,IAZ016", false},
+		// Ranges must run forwards between two three digit zones
+		{"IAZ006>004-212100-", "", true},
+		{"IAZ004>-212100-", "", true},
+		{"IAZ004>0006-212100-", "", true},
+		{"IAZ004>006>008-212100-", "", true},
+		{"IAZ00A>006-212100-", "", true},
+		{"IAZ004-0006-212100-", "", true},
+	}
+	for _, test := range tests {
+		ugc, err := ParseUGC(test.text, issued)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q parsed as %v, want an error", test.text, ugc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.text, err.Error())
+			continue
+		}
+		if codes := strings.Join(ugc.Codes(), ","); codes != test.codes {
+			t.Errorf("%q gave %s, want %s", test.text, codes, test.codes)
+		}
+	}
+}
+
+func TestParseUGCMixed(t *testing.T) {
+	issued := testTime(t, "2024-05-21T20:00:00Z")
+
+	ugc, err := ParseUGC("IAC015-169-IAZ048-LMZ043-212100-", issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ugc.Mixed() {
+		t.Error("counties, public zones and Great Lakes zones in one UGC should be mixed")
+	}
+
+	want := []UGCCode{
+		{"IAC015", UGCCounty},
+		{"IAC169", UGCCounty},
+		{"IAZ048", UGCPublic},
+		{"LMZ043", UGCGreatLakes},
+	}
+	zones := ugc.Zones()
+	if len(zones) != len(want) {
+		t.Fatalf("got zones %v, want %v", zones, want)
+	}
+	for i := range want {
+		if zones[i] != want[i] {
+			t.Errorf("zone %d is %v, want %v", i, zones[i], want[i])
+		}
+	}
+
+	single, err := ParseUGC("IAC015-169-212100-", issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.Mixed() {
+		t.Error("a UGC of counties only is not mixed")
+	}
+}
+
+func TestUGCMarkFire(t *testing.T) {
+	ugc, err := ParseUGC("IAZ048-049-IAC015-ANZ530-212100-", testTime(t, "2024-05-21T20:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ugc.MarkFire()
+
+	// Only the public zones share their codes with the fire zones
+	want := []string{UGCFire, UGCCounty, UGCMarine}
+	for i, state := range ugc.States {
+		if state.Class != want[i] {
+			t.Errorf("%s%s is %s, want %s", state.Name, state.Type, state.Class, want[i])
+		}
+	}
+}
+
+func TestClassifyUGC(t *testing.T) {
+	previous := GetUGCReference()
+	defer SetUGCReference(previous)
+
+	ref := &UGCReference{
+		Zones:     map[string]*UGCZone{},
+		FireZones: map[string]*UGCZone{},
+	}
+	ref.Add(
+		UGCZone{Code: "ANZ530", Type: UGCCoastal},
+		UGCZone{Code: "ANZ810", Type: UGCOffshore},
+		UGCZone{Code: "ANZ900", Type: UGCHighSeas},
+		UGCZone{Code: "IAZ048", Type: UGCPublic},
+		UGCZone{Code: "IAZ048", Type: UGCFire},
+	)
+
+	tests := []struct {
+		code  string
+		class string
+		want  string
+	}{
+		{"ANZ530", UGCMarine, UGCCoastal},
+		{"ANZ810", UGCMarine, UGCOffshore},
+		{"ANZ900", UGCMarine, UGCHighSeas},
+		// Zones that aren't in the reference keep the class of their block
+		{"ANZ999", UGCMarine, UGCMarine},
+		{"IAZ048", UGCPublic, UGCPublic},
+		{"IAZ048", UGCFire, UGCFire},
+		{"IAC015", UGCCounty, UGCCounty},
+		{"LMZ043", UGCGreatLakes, UGCGreatLakes},
+	}
+
+	SetUGCReference(nil)
+	for _, test := range tests {
+		if class := ClassifyUGC(test.code, test.class); class != test.class {
+			t.Errorf("%s without reference data is %s, want %s", test.code, class, test.class)
+		}
+	}
+
+	SetUGCReference(ref)
+	for _, test := range tests {
+		if class := ClassifyUGC(test.code, test.class); class != test.want {
+			t.Errorf("%s %s is %s, want %s", test.code, test.class, class, test.want)
+		}
+	}
+}
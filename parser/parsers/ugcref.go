@@ -0,0 +1,436 @@
+package parsers
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type UGCZone struct {
+	Code     string               `json:"code"`
+	Name     string               `json:"name"`
+	State    string               `json:"state"`
+	Type     string               `json:"type"`
+	WFO      []string             `json:"wfo"`
+	TimeZone []string             `json:"time_zone"`
+	Expires  *time.Time           `json:"expires,omitempty"`
+	Geometry *MultiPolygonFeature `json:"-"`
+}
+
+// Retired returns true if the zone has been removed from service at t
+func (z *UGCZone) Retired(t time.Time) bool {
+	return z.Expires != nil && !z.Expires.After(t)
+}
+
+/*
+UGCReference holds the NWS public zones, fire zones, marine zones and counties.
+It is loaded from GeoJSON, either as downloaded from api.weather.gov or converted from the NWS shapefiles.
+*/
+type UGCReference struct {
+	Zones map[string]*UGCZone
+	// Fire zones reuse the public zone codes so they are kept apart
+	FireZones map[string]*UGCZone
+	// The time zones of each office, worked out the first time they are asked for
+	wfoTimeZones     map[string][]string
+	wfoTimeZonesLock sync.Mutex
+}
+
+type UGCResolution struct {
+	Zones   []UGCZone `json:"zones"`
+	Unknown []string  `json:"unknown,omitempty"`
+	Retired []string  `json:"retired,omitempty"`
+}
+
+var ugcReferenceLock = &sync.Mutex{}
+
+var ugcReference *UGCReference
+
+func SetUGCReference(ref *UGCReference) {
+	ugcReferenceLock.Lock()
+	defer ugcReferenceLock.Unlock()
+
+	ugcReference = ref
+}
+
+// GetUGCReference returns the loaded reference data or nil if none has been loaded
+func GetUGCReference() *UGCReference {
+	ugcReferenceLock.Lock()
+	defer ugcReferenceLock.Unlock()
+
+	return ugcReference
+}
+
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   *struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+func propertyString(properties map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := properties[key]; ok && value != nil {
+			switch v := value.(type) {
+			case string:
+				return v
+			case float64:
+				return fmt.Sprintf("%.0f", v)
+			}
+		}
+	}
+	return ""
+}
+
+func propertyStrings(properties map[string]interface{}, keys ...string) []string {
+	for _, key := range keys {
+		if value, ok := properties[key]; ok && value != nil {
+			switch v := value.(type) {
+			case string:
+				return []string{v}
+			case []interface{}:
+				result := []string{}
+				for _, i := range v {
+					if s, ok := i.(string); ok {
+						result = append(result, s)
+					}
+				}
+				return result
+			}
+		}
+	}
+	return []string{}
+}
+
+/*
+Work out the UGC code of a feature. The NWS API gives the code as the id while the shapefiles
+split it between the state, the zone or FIPS code, and for marine zones an ID field.
+*/
+func featureUGCCode(properties map[string]interface{}, zoneType string) string {
+	if id := propertyString(properties, "id", "ID"); len(id) == 6 {
+		return id
+	}
+	if stateZone := propertyString(properties, "STATE_ZONE"); len(stateZone) == 5 {
+		return stateZone[:2] + "Z" + stateZone[2:]
+	}
+	state := propertyString(properties, "state", "STATE")
+	if zoneType == UGCCounty {
+		fips := propertyString(properties, "FIPS")
+		if len(fips) == 5 {
+			return state + "C" + fips[2:]
+		}
+	}
+	if zone := propertyString(properties, "ZONE"); zone != "" {
+		return state + "Z" + zone
+	}
+	return ""
+}
+
+func geometryToMultiPolygon(geometryType string, coordinates json.RawMessage) (*MultiPolygonFeature, error) {
+	multi := MultiPolygonFeature{
+		Type:        "MultiPolygon",
+		Coordinates: [][][][2]float64{},
+	}
+	switch geometryType {
+	case "Polygon":
+		polygon := [][][2]float64{}
+		if err := json.Unmarshal(coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		multi.Coordinates = append(multi.Coordinates, polygon)
+	case "MultiPolygon":
+		if err := json.Unmarshal(coordinates, &multi.Coordinates); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported UGC geometry type " + geometryType)
+	}
+	return &multi, nil
+}
+
+/*
+ParseUGCGeoJSON reads a GeoJSON feature collection of zones. zoneType is used when the features
+do not carry their own type, as is the case with the shapefiles.
+*/
+func ParseUGCGeoJSON(r io.Reader, zoneType string) ([]UGCZone, error) {
+	collection := geoJSONFeatureCollection{}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, err
+	}
+
+	zones := []UGCZone{}
+	for _, feature := range collection.Features {
+		t := propertyString(feature.Properties, "type")
+		if t == "" {
+			t = zoneType
+		}
+
+		code := featureUGCCode(feature.Properties, t)
+		if code == "" {
+			continue
+		}
+
+		zone := UGCZone{
+			Code:     code,
+			Name:     propertyString(feature.Properties, "name", "NAME", "COUNTYNAME"),
+			State:    propertyString(feature.Properties, "state", "STATE"),
+			Type:     t,
+			WFO:      propertyStrings(feature.Properties, "cwa", "CWA", "WFO"),
+			TimeZone: propertyStrings(feature.Properties, "timeZone", "TIME_ZONE"),
+		}
+		if zone.State == "" {
+			zone.State = code[:2]
+		}
+
+		if expires := propertyString(feature.Properties, "expirationDate"); expires != "" {
+			t, err := time.Parse(time.RFC3339, expires)
+			if err == nil {
+				zone.Expires = &t
+			}
+		}
+
+		if feature.Geometry != nil {
+			geometry, err := geometryToMultiPolygon(feature.Geometry.Type, feature.Geometry.Coordinates)
+			if err != nil {
+				return nil, fmt.Errorf("zone %s: %s", code, err.Error())
+			}
+			zone.Geometry = geometry
+		}
+
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+// The zone type for a file, going by the NWS API download names and the shapefile prefixes
+func zoneTypeFromFile(name string) string {
+	name = strings.ToLower(filepath.Base(name))
+	prefixes := []struct {
+		Prefix string
+		Type   string
+	}{
+		{"county", UGCCounty},
+		{"c_", UGCCounty},
+		{"public", UGCPublic},
+		{"forecast", UGCPublic},
+		{"z_", UGCPublic},
+		{"fire", UGCFire},
+		{"fz", UGCFire},
+		{"coastal", UGCCoastal},
+		{"mz", UGCCoastal},
+		{"offshore", UGCOffshore},
+		{"oz", UGCOffshore},
+		{"highseas", UGCHighSeas},
+		{"hz", UGCHighSeas},
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p.Prefix) {
+			return p.Type
+		}
+	}
+	return ""
+}
+
+// LoadUGCReference loads every .geojson and .json file in dir
+func LoadUGCReference(dir string) (*UGCReference, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := UGCReference{
+		Zones:     map[string]*UGCZone{},
+		FireZones: map[string]*UGCZone{},
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".geojson" && ext != ".json") {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		zones, err := ParseUGCGeoJSON(file, zoneTypeFromFile(entry.Name()))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err.Error())
+		}
+
+		ref.Add(zones...)
+	}
+
+	return &ref, nil
+}
+
+/*
+The baseline zones bundled with the parser, written by --ugc-update --baseline. They have no shapes but are
+enough to name the zones and find the time zones of each office when no downloaded data is available.
+*/
+//go:embed ugc
+var ugcBaseline embed.FS
+
+// LoadUGCBaseline loads the zones bundled with the parser. The reference is empty if none were bundled
+func LoadUGCBaseline() (*UGCReference, error) {
+	ref := UGCReference{
+		Zones:     map[string]*UGCZone{},
+		FireZones: map[string]*UGCZone{},
+	}
+
+	entries, err := ugcBaseline.ReadDir("ugc")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := ugcBaseline.ReadFile("ugc/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		zones := []UGCZone{}
+		if err = json.Unmarshal(data, &zones); err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err.Error())
+		}
+		ref.Add(zones...)
+	}
+
+	return &ref, nil
+}
+
+// Add zones to the reference. Zones split across several features (such as counties shared between offices) are merged
+func (r *UGCReference) Add(zones ...UGCZone) {
+	for i := range zones {
+		zone := zones[i]
+		set := r.Zones
+		if zone.Type == UGCFire {
+			set = r.FireZones
+		}
+		current, ok := set[zone.Code]
+		if !ok {
+			set[zone.Code] = &zone
+			continue
+		}
+
+		for _, wfo := range zone.WFO {
+			found := false
+			for _, w := range current.WFO {
+				if w == wfo {
+					found = true
+				}
+			}
+			if !found {
+				current.WFO = append(current.WFO, wfo)
+			}
+		}
+		if zone.Geometry != nil {
+			if current.Geometry == nil {
+				current.Geometry = zone.Geometry
+			} else {
+				current.Geometry.Coordinates = append(current.Geometry.Coordinates, zone.Geometry.Coordinates...)
+			}
+		}
+	}
+}
+
+func (r *UGCReference) Lookup(code string, fire bool) (*UGCZone, bool) {
+	if fire {
+		zone, ok := r.FireZones[code]
+		return zone, ok
+	}
+	zone, ok := r.Zones[code]
+	return zone, ok
+}
+
+// WFOTimeZones lists the time zones of the zones the office is responsible for, most used first
+func (r *UGCReference) WFOTimeZones(wfo string) []string {
+	r.wfoTimeZonesLock.Lock()
+	defer r.wfoTimeZonesLock.Unlock()
+
+	if zones, ok := r.wfoTimeZones[wfo]; ok {
+		return zones
+	}
+	if r.wfoTimeZones == nil {
+		r.wfoTimeZones = map[string][]string{}
+	}
+
+	counts := map[string]int{}
+	for _, zone := range r.Zones {
+		for _, w := range zone.WFO {
+			if w != wfo {
+				continue
+			}
+			for _, tz := range zone.TimeZone {
+				counts[tz]++
+			}
+		}
+	}
+
+	zones := []string{}
+	for tz := range counts {
+		zones = append(zones, tz)
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		if counts[zones[i]] != counts[zones[j]] {
+			return counts[zones[i]] > counts[zones[j]]
+		}
+		return zones[i] < zones[j]
+	})
+	r.wfoTimeZones[wfo] = zones
+	return zones
+}
+
+// Resolve looks up every zone in the UGC, flagging codes that are unknown or were retired before t
+func (r *UGCReference) Resolve(ugc UGC, t time.Time) UGCResolution {
+	resolution := UGCResolution{
+		Zones: []UGCZone{},
+	}
+
+	for _, code := range ugc.Zones() {
+		zone, ok := r.Lookup(code.Code, code.Class == UGCFire)
+		if !ok {
+			resolution.Unknown = append(resolution.Unknown, code.Code)
+			continue
+		}
+		if zone.Retired(t) {
+			resolution.Retired = append(resolution.Retired, code.Code)
+		}
+		resolution.Zones = append(resolution.Zones, *zone)
+	}
+
+	sort.Strings(resolution.Unknown)
+	sort.Strings(resolution.Retired)
+
+	return resolution
+}
+
+// Geometry combines the shapes of every resolved zone into one multipolygon
+func (r UGCResolution) Geometry() *MultiPolygonFeature {
+	multi := MultiPolygonFeature{
+		Type:        "MultiPolygon",
+		Coordinates: [][][][2]float64{},
+	}
+	for _, zone := range r.Zones {
+		if zone.Geometry != nil {
+			multi.Coordinates = append(multi.Coordinates, zone.Geometry.Coordinates...)
+		}
+	}
+	if len(multi.Coordinates) == 0 {
+		return nil
+	}
+	return &multi
+}
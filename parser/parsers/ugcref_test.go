@@ -0,0 +1,45 @@
+package parsers
+
+import (
+	"testing"
+)
+
+// The bundled zones are read at startup so a bad file should fail here first
+func TestLoadUGCBaseline(t *testing.T) {
+	ref, err := LoadUGCBaseline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An empty bundle would leave the parser without names for any zone
+	if len(ref.Zones) < 51 {
+		t.Fatalf("got %d bundled zones, want at least 51", len(ref.Zones))
+	}
+	for code, zone := range ref.Zones {
+		if len(code) != 6 || zone.Code != code {
+			t.Errorf("bundled zone %q has code %q", code, zone.Code)
+		}
+	}
+
+	known := []struct {
+		code string
+		name string
+	}{
+		{"IAC015", "Boone"},
+		{"IAC153", "Polk"},
+		{"IAC169", "Story"},
+	}
+	for _, k := range known {
+		zone, ok := ref.Lookup(k.code, false)
+		if !ok {
+			t.Errorf("%s is not bundled", k.code)
+			continue
+		}
+		if zone.Name != k.name || zone.Type != UGCCounty || len(zone.WFO) != 1 || zone.WFO[0] != "DMX" {
+			t.Errorf("%s is %+v, want %s county in DMX", k.code, zone, k.name)
+		}
+	}
+
+	if zones := ref.WFOTimeZones("DMX"); len(zones) != 1 || zones[0] != "America/Chicago" {
+		t.Errorf("DMX is in time zones %v, want America/Chicago", zones)
+	}
+}
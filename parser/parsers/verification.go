@@ -0,0 +1,242 @@
+package parsers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// How the verification results are split over time
+const (
+	VerificationPeriodAll   = "all"
+	VerificationPeriodDay   = "day"
+	VerificationPeriodMonth = "month"
+	VerificationPeriodYear  = "year"
+)
+
+// The warnings that are verified against storm reports
+var verifiedPhenomena = []string{"TO", "SV", "FF"}
+
+// A storm-based warning as it was issued
+type VerificationWarning struct {
+	ID        string          `json:"id"`
+	WFO       string          `json:"wfo"`
+	Phenomena string          `json:"phenomena"`
+	Start     time.Time       `json:"start"`
+	End       time.Time       `json:"end"`
+	Polygon   *PolygonFeature `json:"polygon"`
+}
+
+// Verifies returns true if the report is the sort of event the warning is for
+func (w *VerificationWarning) Verifies(report *LSR) bool {
+	return LSRVerifies(w.Phenomena, report)
+}
+
+// Covers returns true if the report happened inside the warning while it was valid
+func (w *VerificationWarning) Covers(report *LSR) bool {
+	if report.Point == nil || w.Polygon == nil || len(w.Polygon.Coordinates) == 0 {
+		return false
+	}
+	if report.Time.Before(w.Start) || report.Time.After(w.End) {
+		return false
+	}
+	return PointInRing(report.Point.Coordinates, w.Polygon.Coordinates[0])
+}
+
+/*
+LSRVerifies returns true if the report verifies a warning of the phenomena. Following the NWS, severe
+thunderstorm warnings are verified by hail of an inch or more, gusts of 58 mph (50 knots) or more, wind
+damage and tornadoes.
+*/
+func LSRVerifies(phenomena string, report *LSR) bool {
+	switch phenomena {
+	case "TO":
+		return report.Type == LSRTornado
+	case "SV":
+		switch report.Type {
+		case LSRTornado:
+			return true
+		case LSRHail:
+			return report.Magnitude != nil && lsrHailInches(report) >= 1
+		case LSRWind:
+			event := strings.ToUpper(report.Event)
+			if strings.Contains(event, "DMG") || strings.Contains(event, "DAMAGE") {
+				return !strings.HasPrefix(event, "NON-TSTM")
+			}
+			if strings.HasPrefix(event, "NON-TSTM") || report.Magnitude == nil {
+				return false
+			}
+			return lsrWindMPH(report) >= 58
+		}
+	case "FF":
+		return report.Type == LSRFlood && strings.Contains(strings.ToUpper(report.Event), "FLASH")
+	}
+	return false
+}
+
+func lsrHailInches(report *LSR) float64 {
+	if strings.HasPrefix(strings.ToUpper(report.Units), "MM") {
+		return *report.Magnitude / 25.4
+	}
+	return *report.Magnitude
+}
+
+func lsrWindMPH(report *LSR) float64 {
+	units := strings.ToUpper(report.Units)
+	switch {
+	case strings.HasPrefix(units, "KT") || strings.HasPrefix(units, "KNOT"):
+		return *report.Magnitude * 1.15078
+	case strings.HasPrefix(units, "KPH") || strings.HasPrefix(units, "KM"):
+		return *report.Magnitude * 0.621371
+	}
+	return *report.Magnitude
+}
+
+type VerificationResult struct {
+	Phenomena   string  `json:"phenomena"`
+	WFO         string  `json:"wfo"`
+	Period      string  `json:"period"`
+	Warnings    int     `json:"warnings"`
+	Verified    int     `json:"verified"`     // Warnings with at least one report (hits)
+	FalseAlarms int     `json:"false_alarms"` // Warnings without any reports
+	Events      int     `json:"events"`
+	Warned      int     `json:"warned"` // Events inside a warning
+	Missed      int     `json:"missed"` // Events outside of every warning
+	POD         float64 `json:"pod"`
+	FAR         float64 `json:"far"`
+	CSI         float64 `json:"csi"`
+	LeadTime    float64 `json:"lead_time"` // Average minutes from the first warning to a warned event
+	leadTotal   time.Duration
+}
+
+func verificationPeriod(t time.Time, period string) string {
+	switch period {
+	case VerificationPeriodDay:
+		return t.UTC().Format("2006-01-02")
+	case VerificationPeriodMonth:
+		return t.UTC().Format("2006-01")
+	case VerificationPeriodYear:
+		return t.UTC().Format("2006")
+	}
+	return VerificationPeriodAll
+}
+
+/*
+Verify matches the reports to the warnings. A warning is verified by any report of its kind that fell inside
+its polygon while it was valid, and a report is warned if any warning of that kind covered it. Results are
+given for each phenomena, office and period.
+*/
+func Verify(warnings []VerificationWarning, reports []LSR, period string) []VerificationResult {
+	results := map[string]*VerificationResult{}
+	result := func(phenomena string, wfo string, t time.Time) *VerificationResult {
+		p := verificationPeriod(t, period)
+		key := phenomena + wfo + p
+		r, ok := results[key]
+		if !ok {
+			r = &VerificationResult{
+				Phenomena: phenomena,
+				WFO:       wfo,
+				Period:    p,
+			}
+			results[key] = r
+		}
+		return r
+	}
+
+	for i := range warnings {
+		warning := &warnings[i]
+		r := result(warning.Phenomena, warning.WFO, warning.Start)
+		r.Warnings++
+
+		verified := false
+		for j := range reports {
+			if warning.Verifies(&reports[j]) && warning.Covers(&reports[j]) {
+				verified = true
+				break
+			}
+		}
+		if verified {
+			r.Verified++
+		} else {
+			r.FalseAlarms++
+		}
+	}
+
+	for _, phenomena := range verifiedPhenomena {
+		for j := range reports {
+			report := &reports[j]
+			if !LSRVerifies(phenomena, report) {
+				continue
+			}
+
+			var first *VerificationWarning
+			for i := range warnings {
+				warning := &warnings[i]
+				if warning.Phenomena != phenomena || !warning.Covers(report) {
+					continue
+				}
+				if first == nil || warning.Start.Before(first.Start) {
+					first = warning
+				}
+			}
+
+			wfo := report.WFO
+			if first != nil {
+				wfo = first.WFO
+			}
+			r := result(phenomena, wfo, report.Time)
+			r.Events++
+			if first == nil {
+				r.Missed++
+				continue
+			}
+			r.Warned++
+			r.leadTotal += report.Time.Sub(first.Start)
+		}
+	}
+
+	list := []VerificationResult{}
+	for _, r := range results {
+		if r.Events > 0 {
+			r.POD = float64(r.Warned) / float64(r.Events)
+		}
+		if r.Warnings > 0 {
+			r.FAR = float64(r.FalseAlarms) / float64(r.Warnings)
+		}
+		// Hits and misses are counted on the events and false alarms on the warnings
+		if total := r.Events + r.FalseAlarms; total > 0 {
+			r.CSI = float64(r.Warned) / float64(total)
+		}
+		if r.Warned > 0 {
+			r.LeadTime = r.leadTotal.Minutes() / float64(r.Warned)
+		}
+		list = append(list, *r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Period != list[j].Period {
+			return list[i].Period < list[j].Period
+		}
+		if list[i].Phenomena != list[j].Phenomena {
+			return list[i].Phenomena < list[j].Phenomena
+		}
+		return list[i].WFO < list[j].WFO
+	})
+
+	return list
+}
+
+// VerificationReport lays the results out as a table
+func VerificationReport(results []VerificationResult) string {
+	builder := strings.Builder{}
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PERIOD\tTYPE\tWFO\tWARNINGS\tVERIFIED\tFALSE ALARMS\tEVENTS\tWARNED\tMISSED\tPOD\tFAR\tCSI\tLEAD (MIN)")
+	for _, r := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.1f\n",
+			r.Period, r.Phenomena, r.WFO, r.Warnings, r.Verified, r.FalseAlarms, r.Events, r.Warned, r.Missed, r.POD, r.FAR, r.CSI, r.LeadTime)
+	}
+	writer.Flush()
+	return builder.String()
+}
@@ -0,0 +1,116 @@
+package parsers
+
+import (
+	"math"
+	"testing"
+)
+
+func testBox(west float64, south float64, east float64, north float64) *PolygonFeature {
+	return &PolygonFeature{
+		Type:        "Polygon",
+		Coordinates: [][][2]float64{{{west, south}, {east, south}, {east, north}, {west, north}, {west, south}}},
+	}
+}
+
+func testReport(t *testing.T, kind string, magnitude float64, units string, at string, lon float64, lat float64) LSR {
+	return LSR{
+		WFO:       "DMX",
+		Time:      testTime(t, at),
+		Type:      kind,
+		Magnitude: &magnitude,
+		Units:     units,
+		Point:     &PointFeature{Type: "Point", Coordinates: [2]float64{lon, lat}},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	warnings := []VerificationWarning{
+		// Verified by the hail and the wind
+		{ID: "1", WFO: "DMX", Phenomena: "SV", Start: testTime(t, "2024-05-21T20:00:00Z"), End: testTime(t, "2024-05-21T21:00:00Z"), Polygon: testBox(-94, 41, -93, 42)},
+		// Nothing reported inside it
+		{ID: "2", WFO: "DMX", Phenomena: "SV", Start: testTime(t, "2024-05-21T20:00:00Z"), End: testTime(t, "2024-05-21T21:00:00Z"), Polygon: testBox(-92, 41, -91, 42)},
+	}
+	reports := []LSR{
+		testReport(t, LSRHail, 1.75, "INCH", "2024-05-21T20:15:00Z", -93.5, 41.5),
+		testReport(t, LSRWind, 52, "KT", "2024-05-21T20:45:00Z", -93.2, 41.2),
+		// Outside of every warning
+		testReport(t, LSRHail, 1, "INCH", "2024-05-21T20:30:00Z", -95.5, 41.5),
+		// Too small to count
+		testReport(t, LSRHail, 0.5, "INCH", "2024-05-21T20:30:00Z", -91.5, 41.5),
+	}
+
+	results := Verify(warnings, reports, VerificationPeriodAll)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	r := results[0]
+
+	if r.Warnings != 2 || r.Verified != 1 || r.FalseAlarms != 1 {
+		t.Errorf("got %d warnings, %d verified and %d false alarms, want 2, 1 and 1", r.Warnings, r.Verified, r.FalseAlarms)
+	}
+	if r.Events != 3 || r.Warned != 2 || r.Missed != 1 {
+		t.Errorf("got %d events, %d warned and %d missed, want 3, 2 and 1", r.Events, r.Warned, r.Missed)
+	}
+
+	scores := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"POD", r.POD, 2.0 / 3.0},
+		{"FAR", r.FAR, 0.5},
+		// 2 warned events over 3 events and 1 false alarm
+		{"CSI", r.CSI, 0.5},
+		{"lead time", r.LeadTime, 30},
+	}
+	for _, score := range scores {
+		if math.Abs(score.got-score.want) > 1e-9 {
+			t.Errorf("got %s %f, want %f", score.name, score.got, score.want)
+		}
+	}
+}
+
+func TestVerifyPeriods(t *testing.T) {
+	warnings := []VerificationWarning{
+		{ID: "1", WFO: "DMX", Phenomena: "TO", Start: testTime(t, "2024-05-21T20:00:00Z"), End: testTime(t, "2024-05-21T21:00:00Z"), Polygon: testBox(-94, 41, -93, 42)},
+		{ID: "2", WFO: "DMX", Phenomena: "TO", Start: testTime(t, "2024-05-22T20:00:00Z"), End: testTime(t, "2024-05-22T21:00:00Z"), Polygon: testBox(-94, 41, -93, 42)},
+	}
+	reports := []LSR{
+		testReport(t, LSRTornado, 0, "", "2024-05-22T20:10:00Z", -93.5, 41.5),
+	}
+
+	// The tornado also counts as a severe thunderstorm event that went unwarned
+	results := Verify(warnings, reports, VerificationPeriodDay)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Period != "2024-05-21" || r.Phenomena != "TO" || r.FalseAlarms != 1 || r.CSI != 0 {
+		t.Errorf("got %+v for the first day", r)
+	}
+	if r := results[1]; r.Period != "2024-05-22" || r.Phenomena != "SV" || r.Missed != 1 || r.CSI != 0 {
+		t.Errorf("got %+v for the severe thunderstorms on the second day", r)
+	}
+	if r := results[2]; r.Period != "2024-05-22" || r.Phenomena != "TO" || r.Verified != 1 || r.CSI != 1 {
+		t.Errorf("got %+v for the tornadoes on the second day", r)
+	}
+}
+
+// Offices write the events and units in mixed case
+func TestLSRVerifiesMixedCase(t *testing.T) {
+	gust := testReport(t, LSRWind, 52, "Kt", "2024-05-21T20:45:00Z", -93.2, 41.2)
+	gust.Event = "Tstm Wnd Gst"
+	damage := testReport(t, LSRWind, 0, "", "2024-05-21T20:45:00Z", -93.2, 41.2)
+	damage.Event = "Tstm Wnd Dmg"
+	nonTstm := testReport(t, LSRWind, 0, "", "2024-05-21T20:45:00Z", -93.2, 41.2)
+	nonTstm.Event = "Non-Tstm Wnd Dmg"
+
+	if !LSRVerifies("SV", &gust) || !LSRVerifies("SV", &damage) {
+		t.Error("mixed case wind reports should verify")
+	}
+	if LSRVerifies("SV", &nonTstm) {
+		t.Error("non-thunderstorm wind damage should not verify")
+	}
+	if lsrType("Flash Flood") != LSRFlood {
+		t.Errorf("Flash Flood is %s", lsrType("Flash Flood"))
+	}
+}
@@ -0,0 +1,224 @@
+package parsers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The status of a single UGC zone within a VTEC event
+const (
+	VTECZoneActive    = "active"
+	VTECZoneCancelled = "cancelled"
+	VTECZoneExpired   = "expired"
+	VTECZoneUpgraded  = "upgraded"
+)
+
+type VTECEventZone struct {
+	UGC          string    `json:"ugc"`
+	Status       string    `json:"status"`
+	Action       string    `json:"action"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Issued       time.Time `json:"issued"`
+	Expires      time.Time `json:"expires"`
+	UpgradedTo   string    `json:"upgraded_to,omitempty"`
+	UpgradedFrom string    `json:"upgraded_from,omitempty"`
+}
+
+/*
+VTECEvent tracks a single P-VTEC event (WFO, phenomena, significance and ETN) zone by zone.
+Segments are applied in the order they were issued and each action is checked against the
+current state of the zones it names so that illegal transitions are rejected.
+*/
+type VTECEvent struct {
+	WFO          string                    `json:"wfo"`
+	Phenomena    string                    `json:"phenomena"`
+	Significance string                    `json:"significance"`
+	ETN          int                       `json:"etn"`
+	Zones        map[string]*VTECEventZone `json:"zones"`
+}
+
+func NewVTECEvent(vtec PVTEC) *VTECEvent {
+	return &VTECEvent{
+		WFO:          vtec.WFO,
+		Phenomena:    vtec.Phenomena,
+		Significance: vtec.Significance,
+		ETN:          vtec.ETN,
+		Zones:        map[string]*VTECEventZone{},
+	}
+}
+
+func (e *VTECEvent) String() string {
+	return fmt.Sprintf("%s.%s.%s.%04d", e.WFO, e.Phenomena, e.Significance, e.ETN)
+}
+
+// Exists returns true once any zone has been added to the event
+func (e *VTECEvent) Exists() bool {
+	return len(e.Zones) > 0
+}
+
+// Active returns true if any zone in the event is still in effect at t
+func (e *VTECEvent) Active(t time.Time) bool {
+	for _, zone := range e.Zones {
+		if zone.Status == VTECZoneActive && zone.End.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the earliest start and the latest end across all of the zones in the event
+func (e *VTECEvent) Bounds() (time.Time, time.Time) {
+	var start, end time.Time
+	for _, zone := range e.Zones {
+		if start.IsZero() || zone.Start.Before(start) {
+			start = zone.Start
+		}
+		if zone.End.After(end) {
+			end = zone.End
+		}
+	}
+	return start, end
+}
+
+func vtecEventKey(vtec PVTEC) string {
+	return fmt.Sprintf("%s.%s.%s.%04d", vtec.WFO, vtec.Phenomena, vtec.Significance, vtec.ETN)
+}
+
+/*
+Apply checks the segment's VTEC action against every zone in its UGC and, if all of them are legal,
+updates the zones. The zones that were changed are returned sorted by UGC code.
+*/
+func (e *VTECEvent) Apply(segment VTECSegment) ([]*VTECEventZone, error) {
+	vtec := segment.VTEC
+
+	if vtec.WFO != e.WFO || vtec.Phenomena != e.Phenomena || vtec.Significance != e.Significance || vtec.ETN != e.ETN {
+		return nil, fmt.Errorf("vtec %s does not belong to event %s", vtec.Original, e.String())
+	}
+
+	// Routine segments change nothing about the event
+	if vtec.Action == "ROU" {
+		return []*VTECEventZone{}, nil
+	}
+
+	switch vtec.Action {
+	case "CON", "EXT", "EXA", "EXB", "COR", "CAN", "EXP", "UPG":
+		if !e.Exists() {
+			return nil, fmt.Errorf("vtec action %s on nonexistent event %s", vtec.Action, e.String())
+		}
+	}
+
+	codes := segment.UGC.Codes()
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("vtec %s has no UGC zones", vtec.Original)
+	}
+
+	// Validate every zone before touching any of them so a bad segment leaves the event untouched
+	for _, code := range codes {
+		zone := e.Zones[code]
+		active := zone != nil && zone.Status == VTECZoneActive
+
+		switch vtec.Action {
+		case "NEW":
+			if active && zone.End.After(segment.Issued) {
+				return nil, fmt.Errorf("vtec NEW on zone %s already active in event %s", code, e.String())
+			}
+		case "CON", "EXT", "COR":
+			if !active {
+				return nil, fmt.Errorf("vtec %s on zone %s not active in event %s", vtec.Action, code, e.String())
+			}
+		case "EXA", "EXB":
+			if active {
+				return nil, fmt.Errorf("vtec %s on zone %s already active in event %s", vtec.Action, code, e.String())
+			}
+		case "CAN", "EXP", "UPG":
+			if zone == nil {
+				return nil, fmt.Errorf("vtec %s on zone %s not in event %s", vtec.Action, code, e.String())
+			}
+			if !active {
+				return nil, fmt.Errorf("vtec %s on zone %s already %s in event %s", vtec.Action, code, zone.Status, e.String())
+			}
+		}
+	}
+
+	// EXB extends the event in time as well as area so there must be something left to extend
+	if vtec.Action == "EXB" {
+		if !e.Active(segment.Issued) {
+			return nil, fmt.Errorf("vtec EXB on event %s that is no longer in effect", e.String())
+		}
+		if vtec.End == nil {
+			return nil, fmt.Errorf("vtec EXB %s has no end time to extend to", vtec.Original)
+		}
+	}
+
+	// Find the other half of an upgrade pair from the rest of the segment
+	pair := ""
+	if vtec.Action == "UPG" || vtec.Action == "NEW" || vtec.Action == "EXA" || vtec.Action == "EXB" {
+		for _, other := range segment.SegmentVTEC {
+			if vtec.Action == "UPG" && other.Action != "UPG" && other.Action != "CAN" {
+				pair = vtecEventKey(other)
+			}
+			if vtec.Action != "UPG" && other.Action == "UPG" {
+				pair = vtecEventKey(other)
+			}
+		}
+	}
+
+	changed := []*VTECEventZone{}
+	for _, code := range codes {
+		zone := e.Zones[code]
+		if zone == nil || (vtec.Action == "NEW" || vtec.Action == "EXA" || vtec.Action == "EXB") && zone.Status != VTECZoneActive {
+			zone = &VTECEventZone{
+				UGC: code,
+			}
+			e.Zones[code] = zone
+		}
+
+		if vtec.Start != nil {
+			zone.Start = *vtec.Start
+		} else if zone.Start.IsZero() {
+			zone.Start = segment.Issued
+		}
+
+		if vtec.End != nil {
+			zone.End = *vtec.End
+		} else if vtec.Action != "CAN" && vtec.Action != "UPG" && vtec.Action != "EXP" {
+			// Until further notice
+			zone.End = segment.Expires
+		}
+
+		zone.Action = vtec.Action
+		zone.Issued = segment.Issued
+		zone.Expires = segment.Expires
+
+		switch vtec.Action {
+		case "CAN":
+			zone.Status = VTECZoneCancelled
+			if zone.End.After(segment.Issued) {
+				zone.End = segment.Issued
+			}
+		case "UPG":
+			zone.Status = VTECZoneUpgraded
+			zone.UpgradedTo = pair
+			if zone.End.After(segment.Issued) {
+				zone.End = segment.Issued
+			}
+		case "EXP":
+			zone.Status = VTECZoneExpired
+		default:
+			zone.Status = VTECZoneActive
+			if pair != "" {
+				zone.UpgradedFrom = pair
+			}
+		}
+
+		changed = append(changed, zone)
+	}
+
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].UGC < changed[j].UGC
+	})
+
+	return changed, nil
+}
@@ -0,0 +1,309 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+)
+
+func testTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// Parse a single product segment the way ParseVTECProduct does
+func testVTECSegments(t *testing.T, text string, issued time.Time) []VTECSegment {
+	t.Helper()
+	segments, err := parseVTECProductSegment(text, &Product{Issued: issued})
+	if err != nil {
+		t.Fatalf("could not parse segment: %s", err.Error())
+	}
+	return segments
+}
+
+type vtecEventStep struct {
+	issued string
+	text   string
+	fails  bool
+	// The status of every zone in each event after the step, keyed by event then UGC
+	want map[string]map[string]string
+}
+
+func TestVTECEventApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []vtecEventStep
+	}{
+		{
+			name: "winter storm warning extended then cancelled",
+			steps: []vtecEventStep{
+				{
+					issued: "2024-01-22T21:05:00Z",
+					text: `IAZ004>006-230600-
+/O.NEW.KDMX.WS.W.0004.240123T0600Z-240124T0000Z/
+Emmet-Kossuth-Winnebago-
+...WINTER STORM WARNING IN EFFECT FROM MIDNIGHT TONIGHT TO 6 PM CST
+TUESDAY...
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneActive, "IAZ005": VTECZoneActive, "IAZ006": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-01-23T03:40:00Z",
+					text: `IAZ004>006-231200-
+/O.CON.KDMX.WS.W.0004.240123T0600Z-240124T0000Z/
+Emmet-Kossuth-Winnebago-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneActive, "IAZ005": VTECZoneActive, "IAZ006": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-01-23T09:30:00Z",
+					text: `IAZ004>006-232200-
+/O.EXT.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Emmet-Kossuth-Winnebago-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneActive, "IAZ005": VTECZoneActive, "IAZ006": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-01-23T21:10:00Z",
+					text: `IAZ004-232315-
+/O.CAN.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Emmet-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneCancelled, "IAZ005": VTECZoneActive, "IAZ006": VTECZoneActive},
+					},
+				},
+				{
+					// The cancelled zone can't be cancelled again
+					issued: "2024-01-23T22:00:00Z",
+					text: `IAZ004-240000-
+/O.CAN.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Emmet-
+`,
+					fails: true,
+				},
+				{
+					// Nor can it be continued
+					issued: "2024-01-23T22:00:00Z",
+					text: `IAZ004-240000-
+/O.CON.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Emmet-
+`,
+					fails: true,
+				},
+				{
+					issued: "2024-01-24T06:05:00Z",
+					text: `IAZ005-006-240715-
+/O.EXP.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Kossuth-Winnebago-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneCancelled, "IAZ005": VTECZoneExpired, "IAZ006": VTECZoneExpired},
+					},
+				},
+				{
+					issued: "2024-01-24T06:30:00Z",
+					text: `IAZ005-240715-
+/O.EXP.KDMX.WS.W.0004.000000T0000Z-240124T0600Z/
+Kossuth-
+`,
+					fails: true,
+				},
+			},
+		},
+		{
+			name: "winter storm watch upgraded to a warning",
+			steps: []vtecEventStep{
+				{
+					issued: "2024-01-21T20:00:00Z",
+					text: `IAZ004-005-221000-
+/O.NEW.KDMX.WS.A.0002.240123T0600Z-240124T0000Z/
+Emmet-Kossuth-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.A.0002": {"IAZ004": VTECZoneActive, "IAZ005": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-01-22T21:05:00Z",
+					text: `IAZ004-005-230600-
+/O.UPG.KDMX.WS.A.0002.240123T0600Z-240124T0000Z/
+/O.NEW.KDMX.WS.W.0004.240123T0600Z-240124T0000Z/
+Emmet-Kossuth-
+`,
+					want: map[string]map[string]string{
+						"DMX.WS.A.0002": {"IAZ004": VTECZoneUpgraded, "IAZ005": VTECZoneUpgraded},
+						"DMX.WS.W.0004": {"IAZ004": VTECZoneActive, "IAZ005": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-01-23T03:40:00Z",
+					text: `IAZ004-005-231200-
+/O.UPG.KDMX.WS.A.0002.240123T0600Z-240124T0000Z/
+Emmet-Kossuth-
+`,
+					fails: true,
+				},
+			},
+		},
+		{
+			name: "severe thunderstorm warning expanded and expired",
+			steps: []vtecEventStep{
+				{
+					issued: "2024-05-21T20:12:00Z",
+					text: `IAC015-169-212100-
+/O.NEW.KDMX.SV.W.0123.240521T2012Z-240521T2100Z/
+Boone IA-Story IA-
+`,
+					want: map[string]map[string]string{
+						"DMX.SV.W.0123": {"IAC015": VTECZoneActive, "IAC169": VTECZoneActive},
+					},
+				},
+				{
+					// Routine segments leave the event alone
+					issued: "2024-05-21T20:20:00Z",
+					text: `IAC015-212100-
+/O.ROU.KDMX.SV.W.0123.000000T0000Z-240521T2100Z/
+Boone IA-
+`,
+					want: map[string]map[string]string{
+						"DMX.SV.W.0123": {"IAC015": VTECZoneActive, "IAC169": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-05-21T20:30:00Z",
+					text: `IAC015-212100-
+/O.EXA.KDMX.SV.W.0123.000000T0000Z-240521T2100Z/
+Boone IA-
+`,
+					fails: true,
+				},
+				{
+					issued: "2024-05-21T20:30:00Z",
+					text: `IAC079-212130-
+/O.EXB.KDMX.SV.W.0123.000000T0000Z-240521T2130Z/
+Hamilton IA-
+`,
+					want: map[string]map[string]string{
+						"DMX.SV.W.0123": {"IAC015": VTECZoneActive, "IAC079": VTECZoneActive, "IAC169": VTECZoneActive},
+					},
+				},
+				{
+					issued: "2024-05-21T21:35:00Z",
+					text: `IAC015-079-169-212145-
+/O.EXP.KDMX.SV.W.0123.000000T0000Z-240521T2130Z/
+Boone IA-Hamilton IA-Story IA-
+`,
+					want: map[string]map[string]string{
+						"DMX.SV.W.0123": {"IAC015": VTECZoneExpired, "IAC079": VTECZoneExpired, "IAC169": VTECZoneExpired},
+					},
+				},
+				{
+					// Nothing is left in effect to extend
+					issued: "2024-05-21T21:40:00Z",
+					text: `IAC127-212200-
+/O.EXB.KDMX.SV.W.0123.000000T0000Z-240521T2200Z/
+Marshall IA-
+`,
+					fails: true,
+				},
+			},
+		},
+		{
+			name: "continuing an event that was never issued",
+			steps: []vtecEventStep{
+				{
+					issued: "2024-05-21T20:12:00Z",
+					text: `IAC015-212100-
+/O.CON.KDMX.SV.W.0124.000000T0000Z-240521T2100Z/
+Boone IA-
+`,
+					fails: true,
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := map[string]*VTECEvent{}
+			for i, step := range test.steps {
+				for _, segment := range testVTECSegments(t, step.text, testTime(t, step.issued)) {
+					key := vtecEventKey(segment.VTEC)
+					event, ok := events[key]
+					if !ok {
+						event = NewVTECEvent(segment.VTEC)
+					}
+
+					_, err := event.Apply(segment)
+					if step.fails {
+						if err == nil {
+							t.Fatalf("step %d: %s was applied but should have been rejected", i, segment.VTEC.Original)
+						}
+						continue
+					}
+					if err != nil {
+						t.Fatalf("step %d: %s", i, err.Error())
+					}
+					events[key] = event
+				}
+
+				for key, zones := range step.want {
+					event, ok := events[key]
+					if !ok {
+						t.Fatalf("step %d: event %s was never created", i, key)
+					}
+					if len(event.Zones) != len(zones) {
+						t.Errorf("step %d: event %s has %d zones, want %d", i, key, len(event.Zones), len(zones))
+					}
+					for code, status := range zones {
+						zone := event.Zones[code]
+						if zone == nil {
+							t.Errorf("step %d: event %s has no zone %s", i, key, code)
+							continue
+						}
+						if zone.Status != status {
+							t.Errorf("step %d: event %s zone %s is %s, want %s", i, key, code, zone.Status, status)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVTECEventUpgradePair(t *testing.T) {
+	segments := testVTECSegments(t, `IAZ004-230600-
+/O.UPG.KDMX.WS.A.0002.240123T0600Z-240124T0000Z/
+/O.NEW.KDMX.WS.W.0004.240123T0600Z-240124T0000Z/
+Emmet-
+`, testTime(t, "2024-01-22T21:05:00Z"))
+
+	watch := NewVTECEvent(segments[0].VTEC)
+	watch.Zones["IAZ004"] = &VTECEventZone{UGC: "IAZ004", Status: VTECZoneActive}
+	zones, err := watch.Apply(segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zones[0].UpgradedTo != "DMX.WS.W.0004" {
+		t.Errorf("watch upgraded to %q, want DMX.WS.W.0004", zones[0].UpgradedTo)
+	}
+
+	warning := NewVTECEvent(segments[1].VTEC)
+	zones, err = warning.Apply(segments[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zones[0].UpgradedFrom != "DMX.WS.A.0002" {
+		t.Errorf("warning upgraded from %q, want DMX.WS.A.0002", zones[0].UpgradedFrom)
+	}
+}
@@ -2,35 +2,38 @@ package parsers
 
 import (
 	"regexp"
-	"strings"
 	"time"
 )
 
 type VTECProduct struct {
 	Product  *Product
 	Segments []VTECSegment
+	Source   string // Where the product came from, NWWS-OI or CAP
 }
 
 type VTECSegment struct {
-	Original     string          `json:"original"`
-	Start        *time.Time      `json:"start"`   // From VTEC
-	End          *time.Time      `json:"end"`     // From VTEC
-	Issued       time.Time       `json:"issued"`  // From WMO line
-	Expires      time.Time       `json:"expires"` // From UGC
-	EventNumber  int             `json:"event_number"`
-	Action       string          `json:"action"`
-	Phenomena    string          `json:"phenomena"`
-	Significance string          `json:"significance"`
-	Polygon      *PolygonFeature `json:"polygon,omitempty"`
-	VTEC         PVTEC           `json:"vtec"`
-	HVETC        *HVTEC          `json:"hvtec,omitempty"` // TODO: Add HVTEC support
-	UGC          UGC             `json:"ugc"`
-	LatLon       *LATLON         `json:"latlon,omitempty"`
-	TML          *TML            `json:"tml,omitempty"`
-	HazardTags   HazardTags      `json:"tags"`
-	Emergency    bool            `json:"emergency"`
-	PDS          bool            `json:"pds"`
-	WFO          string          `json:"wfo"`
+	Original     string               `json:"original"`
+	Start        *time.Time           `json:"start"`   // From VTEC
+	End          *time.Time           `json:"end"`     // From VTEC
+	Issued       time.Time            `json:"issued"`  // From WMO line
+	Expires      time.Time            `json:"expires"` // From UGC
+	EventNumber  int                  `json:"event_number"`
+	Action       string               `json:"action"`
+	Phenomena    string               `json:"phenomena"`
+	Significance string               `json:"significance"`
+	Polygon      *PolygonFeature      `json:"polygon,omitempty"`
+	VTEC         PVTEC                `json:"vtec"`
+	SegmentVTEC  []PVTEC              `json:"segment_vtec,omitempty"` // Every P-VTEC in the segment, including this one
+	HVTEC        *HVTEC               `json:"hvtec,omitempty"`
+	UGC          UGC                  `json:"ugc"`
+	Zones        *UGCResolution       `json:"zones,omitempty"`
+	Geometry     *MultiPolygonFeature `json:"geometry,omitempty"` // From the UGC zones when there is no LAT...LON
+	LatLon       *LATLON              `json:"latlon,omitempty"`
+	TML          *TML                 `json:"tml,omitempty"`
+	HazardTags   HazardTags           `json:"tags"`
+	Emergency    bool                 `json:"emergency"`
+	PDS          bool                 `json:"pds"`
+	WFO          string               `json:"wfo"`
 }
 
 func parseVTECProductSegment(segment string, product *Product) ([]VTECSegment, error) {
@@ -53,7 +56,19 @@ func parseVTECProductSegment(segment string, product *Product) ([]VTECSegment, e
 		return nil, err
 	}
 
-	hvtec := ParseHVTEC(segment, product.Issued)
+	// Fire weather watches and Red Flag Warnings are issued for fire zones rather than public zones
+	for _, vtec := range vtecs {
+		if vtec.Phenomena == "FW" {
+			ugc.MarkFire()
+			break
+		}
+	}
+
+	hvtecs, err := ParseHVTEC(segment)
+	if err != nil {
+		return nil, err
+	}
+	paired := pairHVTEC(segment, vtecs, hvtecs)
 
 	latlon, err := ParseLatLon(segment)
 	if err != nil {
@@ -67,6 +82,17 @@ func parseVTECProductSegment(segment string, product *Product) ([]VTECSegment, e
 		polygon = nil
 	}
 
+	// Resolve the zones to names and shapes when the reference data has been loaded
+	var zones *UGCResolution
+	var geometry *MultiPolygonFeature
+	if ref := GetUGCReference(); ref != nil {
+		resolution := ref.Resolve(*ugc, product.Issued)
+		zones = &resolution
+		if polygon == nil {
+			geometry = resolution.Geometry()
+		}
+	}
+
 	tml, err := ParseTML(segment, product.Issued)
 	if err != nil {
 		return nil, err
@@ -78,7 +104,7 @@ func parseVTECProductSegment(segment string, product *Product) ([]VTECSegment, e
 	hazardTags := ParseHazardTags(segment)
 
 	segments := []VTECSegment{}
-	for _, vtec := range vtecs {
+	for i, vtec := range vtecs {
 		segments = append(segments, VTECSegment{
 			Original:     segment,
 			Start:        vtec.Start,     // From VTEC
@@ -91,8 +117,11 @@ func parseVTECProductSegment(segment string, product *Product) ([]VTECSegment, e
 			Significance: vtec.Significance,
 			Polygon:      polygon,
 			VTEC:         vtec,
-			HVETC:        hvtec,
+			SegmentVTEC:  vtecs,
+			HVTEC:        paired[i],
 			UGC:          *ugc,
+			Zones:        zones,
+			Geometry:     geometry,
 			LatLon:       latlon,
 			TML:          tml,
 			HazardTags:   hazardTags,
@@ -110,16 +139,11 @@ func ParseVTECProduct(product *Product) (*VTECProduct, error) {
 	vtecProduct := VTECProduct{
 		Product:  product,
 		Segments: []VTECSegment{},
+		Source:   SourceNWWS,
 	}
 
-	segments := strings.Split(product.Text, "$$")
-
-	if len(segments) > 1 {
-		segments = segments[:len(segments)-1]
-	}
-
-	for _, s := range segments {
-		segment, err := parseVTECProductSegment(s, product)
+	for _, s := range product.Segments() {
+		segment, err := parseVTECProductSegment(s.Text, product)
 		if err != nil {
 			return nil, err
 		}
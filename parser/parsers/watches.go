@@ -2,11 +2,9 @@ package parsers
 
 import (
 	"errors"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/TheRangiCrew/NWWS-GO/parser/util"
@@ -29,93 +27,231 @@ type WWP struct {
 	PDS                 bool     `json:"pds"`
 }
 
-type Watch struct {
-	ID         string   `json:"id"`
-	Type       string   `json:"type"`
-	Number     int      `json:"number"`
-	WOU        *string  `json:"wou"`
-	WWP        *WWP     `json:"wwp"`
-	SEL        *string  `json:"sel"`
-	SELProduct *Product `json:"-"`
+// The status of a watch as it is assembled and then runs its course
+const (
+	WatchPending   = "pending"   // Waiting for the rest of the products
+	WatchPartial   = "partial"   // Gave up waiting for the rest of the products
+	WatchActive    = "active"    // All of the products have arrived
+	WatchCancelled = "cancelled" // Cancelled early by the SPC
+	WatchReplaced  = "replaced"  // Replaced by a newer watch
+	WatchExpired   = "expired"   // Ran until the end of its valid time
+)
+
+// How long to wait for every product of a watch before keeping what has arrived as a partial watch
+const WatchAssemblyTimeout = 30 * time.Minute
+
+type SAW struct {
+	Original string          `json:"original"`
+	Start    *time.Time      `json:"start,omitempty"`
+	End      *time.Time      `json:"end,omitempty"`
+	Polygon  *PolygonFeature `json:"polygon,omitempty"`
 }
 
-func (w *Watch) IsReady() bool {
-	if w.WWP.Product == nil || *w.SEL == "" || *w.WOU == "" || w.ID == "" || w.Number == 0 {
-		log.Println("Watch " + w.ID + " incomplete. Waiting...")
-		return false
-	}
-	log.Println("Watch " + w.ID + " ready")
-	return true
+/*
+Watch is assembled from the SEL, WWP, WOU and SAW products which can arrive in any order.
+Each parsed product gives a Watch holding just its own piece which is then merged into the stored watch.
+*/
+type Watch struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Number     int                     `json:"number"`
+	Status     string                  `json:"status"`
+	Issued     *time.Time              `json:"issued,omitempty"`
+	Expires    *time.Time              `json:"expires,omitempty"`
+	WOU        *string                 `json:"wou,omitempty"`
+	WWP        *WWP                    `json:"wwp,omitempty"`
+	SEL        *string                 `json:"sel,omitempty"`
+	SAW        *SAW                    `json:"saw,omitempty"`
+	SELProduct *Product                `json:"-"`
+	Polygon    *PolygonFeature         `json:"polygon,omitempty"`  // The watch box from the SAW
+	UGC        *UGC                    `json:"ugc,omitempty"`      // The counties from the WOU
+	Outline    *MultiPolygonFeature    `json:"outline,omitempty"`  // The shape of the counties, if the UGC reference is loaded
+	Counties   map[string]*WatchCounty `json:"counties,omitempty"` // The counties as of the latest update
+	Updates    []WatchUpdate           `json:"updates,omitempty"`
+	Replaces   []string                `json:"replaces,omitempty"`
+	ReplacedBy string                  `json:"replaced_by,omitempty"`
+	Cancelled  *time.Time              `json:"cancelled,omitempty"` // When the SPC cancelled the whole watch
+	FirstSeen  time.Time               `json:"first_seen"`
+	UpdatedAt  time.Time               `json:"updated_at"`
 }
 
-var lock = &sync.Mutex{}
+func WatchID(phenomena string, number int, year int) string {
+	return phenomena + "A" + util.PadZero(strconv.Itoa(number), 4) + strconv.Itoa(year)
+}
 
-var queue *[]Watch
+/*
+WatchYear works out which year's numbering a watch belongs to the same way EventYear does for VTEC events, as
+watch numbers also start again each year. start is when the watch began, if the product gives it. lookup should
+return the expiry of the watch numbered in a year, or nil if there isn't one.
+*/
+func WatchYear(issued time.Time, start *time.Time, lookup func(year int) (*time.Time, error)) (int, error) {
+	return PVTEC{Start: start}.EventYear(issued, lookup)
+}
 
-func Queue() *[]Watch {
-	lock.Lock()
-	defer lock.Unlock()
+// SetYear gives the watch the ID it has in the numbering of the year
+func (w *Watch) SetYear(year int) {
+	w.ID = WatchID(w.Type, w.Number, year)
+}
 
-	if queue == nil {
-		queue = &[]Watch{}
+// The ID is taken from the year the product was issued until SetYear is given the year the watch was numbered in
+func newWatchPiece(phenomena string, number int, issued time.Time) *Watch {
+	return &Watch{
+		ID:        WatchID(phenomena, number, issued.Year()),
+		Type:      phenomena,
+		Number:    number,
+		Status:    WatchPending,
+		FirstSeen: issued,
+		UpdatedAt: issued,
 	}
+}
 
-	return queue
+// Complete returns true once the SEL, WWP, WOU and SAW have all arrived
+func (w *Watch) Complete() bool {
+	return w.SEL != nil && w.WWP != nil && w.WOU != nil && w.SAW != nil
 }
 
-func findWatch(t string, n int) *Watch {
-	q := Queue()
+// Missing lists the products the watch is still waiting for
+func (w *Watch) Missing() []string {
+	missing := []string{}
+	if w.SEL == nil {
+		missing = append(missing, "SEL")
+	}
+	if w.WWP == nil {
+		missing = append(missing, "WWP")
+	}
+	if w.WOU == nil {
+		missing = append(missing, "WOU")
+	}
+	if w.SAW == nil {
+		missing = append(missing, "SAW")
+	}
+	return missing
+}
 
-	for _, w := range *q {
-		if w.Number == n && w.Type == t {
-			foundWatch := w    // Create a local variable and assign the value of 'w'
-			return &foundWatch // Return the address of 'foundWatch'
-		}
+// Merge adds the pieces of another product of the same watch
+func (w *Watch) Merge(piece *Watch) error {
+	if piece.ID != w.ID {
+		return errors.New("tried to merge watch " + piece.ID + " into " + w.ID)
 	}
 
-	return nil
-}
+	if piece.SEL != nil {
+		w.SEL = piece.SEL
+		w.SELProduct = piece.SELProduct
+	}
+	if piece.WWP != nil {
+		w.WWP = piece.WWP
+	}
+	if piece.WOU != nil {
+		w.WOU = piece.WOU
+		w.UGC = piece.UGC
+		w.Outline = piece.Outline
+	}
+	if piece.SAW != nil {
+		w.SAW = piece.SAW
+		w.Polygon = piece.Polygon
+	}
 
-func createWatch(t string, n int, issued time.Time) (*Watch, error) {
-	current := findWatch(t, n)
+	if piece.Issued != nil && (w.Issued == nil || piece.Issued.Before(*w.Issued)) {
+		w.Issued = piece.Issued
+	}
+	// The latest product knows best when the watch ends
+	if piece.Expires != nil && (w.Expires == nil || !piece.UpdatedAt.Before(w.UpdatedAt)) {
+		w.Expires = piece.Expires
+	}
 
-	if current != nil {
-		return current, nil
+	for _, r := range piece.Replaces {
+		found := false
+		for _, current := range w.Replaces {
+			if current == r {
+				found = true
+			}
+		}
+		if !found {
+			w.Replaces = append(w.Replaces, r)
+		}
 	}
 
-	q := Queue()
+	for _, update := range piece.Updates {
+		w.AddUpdate(update)
+	}
 
-	id := t + "A" + util.PadZero(strconv.Itoa(n), 4) + strconv.Itoa(issued.Year())
+	if piece.ReplacedBy != "" {
+		w.ReplacedBy = piece.ReplacedBy
+	}
 
-	s := ""
-	w := ""
+	// The SEL, WWA and WOU can all carry the cancellation so keep the first
+	if piece.Cancelled != nil && (w.Cancelled == nil || piece.Cancelled.Before(*w.Cancelled)) {
+		w.Cancelled = piece.Cancelled
+	}
+	if w.Cancelled != nil && (w.Expires == nil || w.Cancelled.Before(*w.Expires)) {
+		w.Expires = w.Cancelled
+	}
 
-	watch := Watch{
-		ID:         id,
-		Type:       t,
-		Number:     n,
-		SEL:        &s,
-		WOU:        &w,
-		SELProduct: &Product{},
-		WWP:        &WWP{},
+	// Cancellations and replacements stick
+	switch piece.Status {
+	case WatchCancelled, WatchReplaced:
+		w.Status = piece.Status
 	}
 
-	*q = append(*q, watch)
+	if piece.FirstSeen.Before(w.FirstSeen) {
+		w.FirstSeen = piece.FirstSeen
+	}
+	if piece.UpdatedAt.After(w.UpdatedAt) {
+		w.UpdatedAt = piece.UpdatedAt
+	}
 
-	result := findWatch(t, n)
+	return nil
+}
 
-	if result == nil {
-		return &Watch{}, errors.New("tried to create watch but failed somehow")
+// UpdateStatus works out the status of the watch at now. It returns true if the status changed
+func (w *Watch) UpdateStatus(now time.Time) bool {
+	status := w.Status
+
+	switch {
+	case w.Status == WatchCancelled || w.Status == WatchReplaced:
+	case w.Expires != nil && !now.Before(*w.Expires):
+		status = WatchExpired
+	// Every county has been cleared by the WFOs before the watch was due to end
+	case len(w.Updates) > 0 && len(w.CountiesAt(now)) == 0:
+		status = WatchCancelled
+	case w.Complete():
+		status = WatchActive
+	case now.Sub(w.FirstSeen) > WatchAssemblyTimeout:
+		status = WatchPartial
+	default:
+		status = WatchPending
 	}
 
-	log.Println("Watch Created " + watch.ID)
+	changed := status != w.Status
+	w.Status = status
 
-	return result, nil
+	return changed
+}
+
+// Find the valid time of the watch, e.g. 191950Z - 200300Z
+func parseWatchValid(text string, issued time.Time) (*time.Time, *time.Time) {
+	validRegexp := regexp.MustCompile(`([0-9]{6})Z\s*-\s*([0-9]{6})Z`)
+	valid := validRegexp.FindStringSubmatch(text)
+	if valid == nil {
+		return nil, nil
+	}
+	start, err := ddhhmmNear(valid[1], issued)
+	if err != nil {
+		return nil, nil
+	}
+	end, err := ddhhmmNear(valid[2], start)
+	if err != nil {
+		return nil, nil
+	}
+	return &start, &end
 }
 
 func parseWOU(product *Product) (*Watch, error) {
 	watchIDRegexp := regexp.MustCompile("(WS|WT) ([0-9]{1,4})")
 	watchID := strings.Split(watchIDRegexp.FindString(product.Text), " ")
+	if len(watchID) != 2 {
+		return nil, errors.New("failed to find WOU watch number")
+	}
 
 	var phenomena string
 	if watchID[0] == "WS" {
@@ -134,17 +270,122 @@ func parseWOU(product *Product) (*Watch, error) {
 		return nil, errors.New("failed to parse WWP watch number")
 	}
 
-	watch := findWatch(phenomena, number)
+	watch := newWatchPiece(phenomena, number, product.Issued)
+	text := product.Text
+	watch.WOU = &text
 
-	if watch == nil {
-		watch, err = createWatch(phenomena, number, product.Issued)
+	// The SPC's own VTEC gives the watch's valid time and tells us when it is cancelled
+	ugc, err := ParseUGC(product.Text, product.Issued)
+	if err != nil {
+		return nil, err
+	}
+	if ugc != nil {
+		vtecs, err := ParsePVTEC(product.Text, product.Issued, *ugc)
+		if err != nil {
+			return nil, err
+		}
+		for _, vtec := range vtecs {
+			if vtec.Phenomena != phenomena || vtec.ETN != number {
+				continue
+			}
+			switch vtec.Action {
+			case "CAN":
+				watch.cancel(product.Issued)
+			case "EXP":
+				watch.Expires = vtec.End
+			default:
+				if vtec.Start != nil {
+					watch.Issued = vtec.Start
+				}
+				if vtec.End != nil {
+					watch.Expires = vtec.End
+				}
+			}
+		}
+	}
+
+	updates, err := ParseWatchUpdates(product)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		if u.ID == watch.ID {
+			for _, update := range u.Updates {
+				watch.AddUpdate(update)
+			}
+		}
+	}
 
+	// Each state has its own segment and UGC so gather them all into one county list
+	counties := UGC{
+		States: []State{},
+	}
+	originals := []string{}
+	for _, s := range product.Segments() {
+		segment := s.Text
+		u, err := ParseUGC(segment, product.Issued)
 		if err != nil {
 			return nil, err
 		}
+		if u == nil {
+			continue
+		}
+		counties.States = append(counties.States, u.States...)
+		originals = append(originals, u.Original)
+		if u.Expires.After(counties.Expires) {
+			counties.Expires = u.Expires
+		}
+	}
+	if len(counties.States) > 0 {
+		counties.Original = strings.Join(originals, "\n")
+		watch.UGC = &counties
+		if ref := GetUGCReference(); ref != nil {
+			watch.Outline = ref.Resolve(counties, product.Issued).Geometry()
+		}
+	}
+
+	return watch, nil
+}
+
+// Parse the SAW aviation watch approximation for the watch box and valid time
+func parseSAW(product *Product) (*Watch, error) {
+	idRegexp := regexp.MustCompile(`(?m:^\s*WW ([0-9]+) (TORNADO|SEVERE TSTM))`)
+	id := idRegexp.FindStringSubmatch(product.Text)
+	if id == nil {
+		return nil, errors.New("failed to find SAW watch number")
 	}
 
-	*watch.WOU = product.Text
+	number, err := strconv.Atoi(id[1])
+	if err != nil {
+		return nil, errors.New("failed to parse SAW watch number")
+	}
+
+	phenomena := "SV"
+	if id[2] == "TORNADO" {
+		phenomena = "TO"
+	}
+
+	latlon, err := ParseLatLon(product.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := parseWatchValid(product.Text, product.Issued)
+
+	saw := SAW{
+		Original: product.Text,
+		Start:    start,
+		End:      end,
+	}
+	if latlon != nil {
+		saw.Polygon = latlon.Polygon
+	}
+
+	watch := newWatchPiece(phenomena, number, product.Issued)
+	watch.SAW = &saw
+	watch.Polygon = saw.Polygon
+	watch.Issued = start
+	watch.Expires = end
 
 	return watch, nil
 }
@@ -152,6 +393,9 @@ func parseWOU(product *Product) (*Watch, error) {
 func parseWWP(product *Product) (*Watch, error) {
 	watchIDRegexp := regexp.MustCompile("(WS|WT) ([0-9]{4})")
 	watchID := strings.Split(watchIDRegexp.FindString(product.Text), " ")
+	if len(watchID) != 2 {
+		return nil, errors.New("failed to find WWP watch number")
+	}
 
 	var phenomena string
 	if watchID[0] == "WS" {
@@ -261,17 +505,9 @@ func parseWWP(product *Product) (*Watch, error) {
 		PDS:                 pds,
 	}
 
-	watch := findWatch(phenomena, number)
-
-	if watch == nil {
-		watch, err = createWatch(phenomena, number, product.Issued)
-
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	*watch.WWP = wwp
+	watch := newWatchPiece(phenomena, number, product.Issued)
+	watch.WWP = &wwp
+	watch.Issued, watch.Expires = parseWatchValid(product.Text, product.Issued)
 
 	return watch, nil
 }
@@ -281,6 +517,9 @@ func parseSEL(product *Product) (*Watch, error) {
 
 	idLineRegexp := regexp.MustCompile(`(Severe Thunderstorm|Tornado)( Watch Number )[0-9]+`)
 	idLine := idLineRegexp.FindString(original)
+	if idLine == "" {
+		return nil, errors.New("failed to find SEL watch number")
+	}
 
 	phenomenaRegexp := regexp.MustCompile(`(Severe Thunderstorm|Tornado)`)
 	phenomenaString := phenomenaRegexp.FindString(idLine)
@@ -299,18 +538,70 @@ func parseSEL(product *Product) (*Watch, error) {
 		return nil, errors.New("failed to parse SEL watch number")
 	}
 
-	watch := findWatch(phenomena, number)
+	watch := newWatchPiece(phenomena, number, product.Issued)
+	watch.SELProduct = product
+	watch.SEL = &original
+
+	// e.g. This Tornado Watch replaces Severe Thunderstorm Watch number 25
+	replacesRegexp := regexp.MustCompile(`(?is)replaces\s+(.+?)\.`)
+	replacedRegexp := regexp.MustCompile(`(?i)(Severe Thunderstorm|Tornado)\s+Watch\s+(?:Number\s+)?([0-9]+)`)
+	for _, sentence := range replacesRegexp.FindAllStringSubmatch(original, -1) {
+		for _, replaced := range replacedRegexp.FindAllStringSubmatch(sentence[1], -1) {
+			n, err := strconv.Atoi(replaced[2])
+			if err != nil {
+				continue
+			}
+			t := "TO"
+			if strings.EqualFold(replaced[1], "Severe Thunderstorm") {
+				t = "SV"
+			}
+			id := WatchID(t, n, product.Issued.Year())
+			if id != watch.ID {
+				watch.Replaces = append(watch.Replaces, id)
+			}
+		}
+	}
 
-	if watch == nil {
-		watch, err = createWatch(phenomena, number, product.Issued)
+	return watch, nil
+}
 
-		if err != nil {
-			return nil, err
-		}
+// Mark the whole watch as cancelled at t
+func (w *Watch) cancel(t time.Time) {
+	w.Status = WatchCancelled
+	w.Cancelled = &t
+	w.Expires = &t
+}
+
+// e.g. The NWS Storm Prediction Center has cancelled Tornado Watch 25 or SEVERE THUNDERSTORM WATCH 301 CANCELLED
+var watchCancelledRegexp = regexp.MustCompile(`(?i)cancell?ed\s+(?:the\s+)?(Severe Thunderstorm|Tornado)\s+Watch\s+(?:Number\s+)?([0-9]+)|(Severe Thunderstorm|Tornado)\s+Watch\s+(?:Number\s+)?([0-9]+)\s+(?:is\s+|has\s+been\s+)?cancell?ed`)
+
+/*
+parseWatchCancellation finds the watch a SEL or WWA cancels. It returns nil if the product does not
+cancel a watch.
+*/
+func parseWatchCancellation(product *Product) (*Watch, error) {
+	match := watchCancelledRegexp.FindStringSubmatch(product.Text)
+	if match == nil {
+		return nil, nil
+	}
+
+	name, numberString := match[1], match[2]
+	if name == "" {
+		name, numberString = match[3], match[4]
 	}
 
-	*watch.SELProduct = *product
-	*watch.SEL = original
+	number, err := strconv.Atoi(numberString)
+	if err != nil {
+		return nil, errors.New("failed to parse cancelled watch number")
+	}
+
+	phenomena := "TO"
+	if strings.EqualFold(name, "Severe Thunderstorm") {
+		phenomena = "SV"
+	}
+
+	watch := newWatchPiece(phenomena, number, product.Issued)
+	watch.cancel(product.Issued)
 
 	return watch, nil
 }
@@ -322,9 +613,20 @@ func ParseWatchProduct(product *Product) (*Watch, error) {
 	case "WWP":
 		watch, err = parseWWP(product)
 	case "SEL":
-		watch, err = parseSEL(product)
+		// A SEL is also sent when a watch is cancelled early
+		watch, err = parseWatchCancellation(product)
+		if err == nil && watch == nil {
+			watch, err = parseSEL(product)
+		}
+	case "WWA":
+		watch, err = parseWatchCancellation(product)
+		if err == nil && watch == nil {
+			err = errors.New("failed to find a cancelled watch in the WWA")
+		}
 	case "WOU":
 		watch, err = parseWOU(product)
+	case "SAW":
+		watch, err = parseSAW(product)
 	}
 
 	return watch, err
@@ -0,0 +1,230 @@
+package parsers
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchYear(t *testing.T) {
+	tests := []struct {
+		name   string
+		issued string
+		start  string
+		// The expiry of the stored watch for each year
+		watches map[int]string
+		want    int
+	}{
+		{
+			name:    "issued on Dec 31 and updated on Jan 1",
+			issued:  "2024-01-01T01:30:00Z",
+			watches: map[int]string{2023: "2024-01-01T04:00:00Z"},
+			want:    2023,
+		},
+		{
+			name:    "update with the watch start in the old year",
+			issued:  "2024-01-01T01:30:00Z",
+			start:   "2023-12-31T22:00:00Z",
+			watches: map[int]string{2023: "2024-01-01T04:00:00Z", 2024: "2024-01-01T06:00:00Z"},
+			want:    2023,
+		},
+		{
+			name:    "first watch of the new year",
+			issued:  "2024-01-09T18:00:00Z",
+			start:   "2024-01-09T18:00:00Z",
+			watches: map[int]string{2023: "2023-01-03T04:00:00Z"},
+			want:    2024,
+		},
+		{
+			name:   "nothing stored yet",
+			issued: "2024-05-21T18:00:00Z",
+			want:   2024,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var start *time.Time
+			if test.start != "" {
+				s := testTime(t, test.start)
+				start = &s
+			}
+			year, err := WatchYear(testTime(t, test.issued), start, func(year int) (*time.Time, error) {
+				expires, ok := test.watches[year]
+				if !ok {
+					return nil, nil
+				}
+				e := testTime(t, expires)
+				return &e, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if year != test.want {
+				t.Errorf("got year %d, want %d", year, test.want)
+			}
+
+			watch := newWatchPiece("TO", 1, testTime(t, test.issued))
+			watch.SetYear(year)
+			if want := WatchID("TO", 1, test.want); watch.ID != want {
+				t.Errorf("got ID %s, want %s", watch.ID, want)
+			}
+		})
+	}
+}
+
+func TestParseWatchCancellation(t *testing.T) {
+	tests := []struct {
+		awips string
+		text  string
+		want  string
+	}{
+		{"SEL", "The NWS Storm Prediction Center has cancelled\nTornado Watch 25 for portions of\nIowa\n", "TOA00252024"},
+		{"WWA", "SEVERE THUNDERSTORM WATCH NUMBER 301 HAS BEEN CANCELLED.\n", "SVA03012024"},
+		{"WWA", "Severe Thunderstorm Watch 301 is canceled\n", "SVA03012024"},
+	}
+
+	issued := testTime(t, "2024-05-21T22:00:00Z")
+	for _, test := range tests {
+		watch, err := ParseWatchProduct(&Product{Text: test.text, AWIPS: AWIPS{Product: test.awips}, Issued: issued})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if watch.ID != test.want {
+			t.Errorf("got watch %s, want %s", watch.ID, test.want)
+		}
+		if watch.Status != WatchCancelled || watch.Cancelled == nil || !watch.Cancelled.Equal(issued) {
+			t.Errorf("%s was not cancelled at %s", watch.ID, issued)
+		}
+	}
+
+	if _, err := ParseWatchProduct(&Product{Text: "Nothing to see here\n", AWIPS: AWIPS{Product: "WWA"}, Issued: issued}); err == nil {
+		t.Error("a WWA without a cancelled watch should fail")
+	}
+}
+
+func TestWatchCountiesAtCancelled(t *testing.T) {
+	start := testTime(t, "2024-05-21T18:00:00Z")
+	expires := testTime(t, "2024-05-22T02:00:00Z")
+
+	watch := newWatchPiece("TO", 25, start)
+	watch.Expires = &expires
+	watch.AddUpdate(WatchUpdate{
+		Product:  "WOU",
+		Issued:   start,
+		Action:   "NEW",
+		Counties: []string{"IAC015", "IAC169"},
+		Expires:  &expires,
+	})
+
+	cancellation := newWatchPiece("TO", 25, testTime(t, "2024-05-21T22:00:00Z"))
+	cancellation.cancel(cancellation.FirstSeen)
+	if err := watch.Merge(cancellation); err != nil {
+		t.Fatal(err)
+	}
+
+	if counties := watch.CountiesAt(testTime(t, "2024-05-21T21:00:00Z")); len(counties) != 2 {
+		t.Errorf("got %v before the cancellation, want both counties", counties)
+	}
+	if counties := watch.CountiesAt(testTime(t, "2024-05-21T23:00:00Z")); len(counties) != 0 {
+		t.Errorf("got %v after the cancellation, want none", counties)
+	}
+	if !watch.Expires.Equal(*watch.Cancelled) {
+		t.Errorf("watch expires at %s, want the cancellation", watch.Expires)
+	}
+	if watch.Status != WatchCancelled {
+		t.Errorf("watch is %s, want %s", watch.Status, WatchCancelled)
+	}
+}
+
+// The SAW and WWP have no issued line so the time is given rather than taken from the WMO line
+func testWatchPiece(t *testing.T, name string, issued time.Time) *Watch {
+	t.Helper()
+	text, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := NewAWIPSProduct(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Issued = issued
+	watch, err := product.WatchProduct()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return watch
+}
+
+func TestParseSAW(t *testing.T) {
+	saw := testWatchPiece(t, "SAW0.txt", testTime(t, "2024-05-21T20:05:00Z"))
+
+	if saw.ID != "TOA02502024" {
+		t.Errorf("got watch %s, want TOA02502024", saw.ID)
+	}
+	if saw.SAW == nil || saw.Polygon == nil || saw.Polygon != saw.SAW.Polygon {
+		t.Fatal("the SAW should give the watch box")
+	}
+	// The box is closed back onto its first point
+	if points := saw.Polygon.Coordinates[0]; len(points) != 5 || points[0] != [2]float64{-94.78, 41.34} {
+		t.Errorf("got watch box %v", points)
+	}
+	if saw.Issued == nil || !saw.Issued.Equal(testTime(t, "2024-05-21T20:05:00Z")) {
+		t.Errorf("got issued %v, want 20:05 UTC", saw.Issued)
+	}
+	if saw.Expires == nil || !saw.Expires.Equal(testTime(t, "2024-05-22T03:00:00Z")) {
+		t.Errorf("got expires %v, want 03:00 UTC the next day", saw.Expires)
+	}
+}
+
+func TestWatchAssembly(t *testing.T) {
+	issued := testTime(t, "2024-05-21T20:05:00Z")
+
+	watch := testWatchPiece(t, "SAW0.txt", issued)
+	if watch.UpdateStatus(issued); watch.Status != WatchPending {
+		t.Errorf("watch is %s with just the SAW, want %s", watch.Status, WatchPending)
+	}
+
+	wwp := testWatchPiece(t, "WWP0.txt", issued)
+	if wwp.WWP == nil || wwp.WWP.TwoOrMoreTor != "60%" || wwp.WWP.MaxHail != 2.0 || wwp.WWP.Degrees != 240 || wwp.WWP.Speed != 30 {
+		t.Errorf("got WWP %+v", wwp.WWP)
+	}
+
+	sel := "Tornado Watch Number 250"
+	selPiece := newWatchPiece("TO", 250, issued)
+	selPiece.SEL = &sel
+
+	for _, piece := range []*Watch{wwp, selPiece} {
+		if err := watch.Merge(piece); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Merging the other parts keeps the box from the SAW
+	if watch.Polygon == nil || watch.WWP == nil || watch.SEL == nil {
+		t.Fatal("merging lost part of the watch")
+	}
+	if missing := watch.Missing(); len(missing) != 1 || missing[0] != "WOU" {
+		t.Errorf("watch is missing %v, want just the WOU", missing)
+	}
+
+	if watch.UpdateStatus(issued.Add(10 * time.Minute)); watch.Status != WatchPending {
+		t.Errorf("watch is %s while waiting for the WOU, want %s", watch.Status, WatchPending)
+	}
+	if !watch.UpdateStatus(issued.Add(WatchAssemblyTimeout+time.Minute)) || watch.Status != WatchPartial {
+		t.Errorf("watch is %s after the assembly timeout, want %s", watch.Status, WatchPartial)
+	}
+
+	wou := "WOU0"
+	wouPiece := newWatchPiece("TO", 250, issued.Add(5*time.Minute))
+	wouPiece.WOU = &wou
+	if err := watch.Merge(wouPiece); err != nil {
+		t.Fatal(err)
+	}
+	if !watch.UpdateStatus(issued.Add(WatchAssemblyTimeout+2*time.Minute)) || watch.Status != WatchActive {
+		t.Errorf("watch is %s with every part, want %s", watch.Status, WatchActive)
+	}
+
+	if watch.UpdateStatus(testTime(t, "2024-05-22T03:00:00Z")); watch.Status != WatchExpired {
+		t.Errorf("watch is %s at its end, want %s", watch.Status, WatchExpired)
+	}
+}
@@ -0,0 +1,174 @@
+package parsers
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// The state of a county within a watch
+const (
+	WatchCountyActive    = "active"
+	WatchCountyCancelled = "cancelled"
+	WatchCountyExpired   = "expired"
+)
+
+type WatchCounty struct {
+	UGC     string     `json:"ugc"`
+	Status  string     `json:"status"`
+	Action  string     `json:"action"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Updated time.Time  `json:"updated"`
+}
+
+/*
+WatchUpdate is a county level change to a watch from the SPC's WOU or a WFO's WCN.
+The updates are kept in full so the counties of a watch can be worked out at any time.
+*/
+type WatchUpdate struct {
+	Product  string     `json:"product"` // The text product ID
+	WFO      string     `json:"wfo"`
+	Issued   time.Time  `json:"issued"`
+	Action   string     `json:"action"`
+	Counties []string   `json:"counties"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+func (u WatchUpdate) key() string {
+	return u.Product + u.Action + strings.Join(u.Counties, "")
+}
+
+// Add an update to the watch, skipping any it already has, and work out the counties again
+func (w *Watch) AddUpdate(update WatchUpdate) {
+	for _, current := range w.Updates {
+		if current.key() == update.key() {
+			return
+		}
+	}
+
+	w.Updates = append(w.Updates, update)
+	// Products can arrive out of order so keep the updates in the order they were issued
+	sort.SliceStable(w.Updates, func(i, j int) bool {
+		return w.Updates[i].Issued.Before(w.Updates[j].Issued)
+	})
+
+	w.Counties = w.replayUpdates(nil)
+}
+
+/*
+Replay the updates to get the state of every county. When at is given only the updates issued by then
+are used and counties that ran out by then are expired.
+*/
+func (w *Watch) replayUpdates(at *time.Time) map[string]*WatchCounty {
+	counties := map[string]*WatchCounty{}
+
+	for _, update := range w.Updates {
+		if at != nil && update.Issued.After(*at) {
+			break
+		}
+		for _, code := range update.Counties {
+			county, ok := counties[code]
+			if !ok {
+				county = &WatchCounty{
+					UGC: code,
+				}
+				counties[code] = county
+			}
+
+			county.Action = update.Action
+			county.Updated = update.Issued
+			switch update.Action {
+			case "CAN":
+				county.Status = WatchCountyCancelled
+			case "EXP":
+				county.Status = WatchCountyExpired
+			default:
+				// NEW, EXA and EXB add counties while CON and EXT carry them on
+				county.Status = WatchCountyActive
+				if update.Expires != nil {
+					county.Expires = update.Expires
+				}
+			}
+		}
+	}
+
+	if at != nil {
+		for _, county := range counties {
+			if county.Status == WatchCountyActive && county.Expires != nil && !at.Before(*county.Expires) {
+				county.Status = WatchCountyExpired
+			}
+		}
+	}
+
+	return counties
+}
+
+// CountiesAt returns the UGC codes of the counties that were in the watch at t
+func (w *Watch) CountiesAt(t time.Time) []string {
+	active := []string{}
+	if w.Cancelled != nil && !t.Before(*w.Cancelled) {
+		return active
+	}
+	for code, county := range w.replayUpdates(&t) {
+		if county.Status == WatchCountyActive {
+			active = append(active, code)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
+
+/*
+ParseWatchUpdates finds the county updates for every watch in a product with TO.A or SV.A VTEC,
+such as the WOU and WCN. Each watch is given as a piece to be merged into the stored watch.
+*/
+func ParseWatchUpdates(product *Product) ([]*Watch, error) {
+	watches := []*Watch{}
+	found := map[string]*Watch{}
+
+	for _, s := range product.Segments() {
+		segment := s.Text
+		ugc, err := ParseUGC(segment, product.Issued)
+		if err != nil {
+			return nil, err
+		}
+		if ugc == nil {
+			continue
+		}
+
+		vtecs, err := ParsePVTEC(segment, product.Issued, *ugc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, vtec := range vtecs {
+			if vtec.Significance != "A" || (vtec.Phenomena != "TO" && vtec.Phenomena != "SV") {
+				continue
+			}
+
+			id := WatchID(vtec.Phenomena, vtec.ETN, product.Issued.Year())
+			watch, ok := found[id]
+			if !ok {
+				watch = newWatchPiece(vtec.Phenomena, vtec.ETN, product.Issued)
+				found[id] = watch
+				watches = append(watches, watch)
+			}
+
+			expires := vtec.End
+			if expires == nil {
+				expires = &ugc.Expires
+			}
+
+			watch.AddUpdate(WatchUpdate{
+				Product:  product.ID,
+				WFO:      vtec.WFO,
+				Issued:   product.Issued,
+				Action:   vtec.Action,
+				Counties: ugc.Codes(),
+				Expires:  expires,
+			})
+		}
+	}
+
+	return watches, nil
+}
@@ -0,0 +1,70 @@
+package parsers
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseWatchUpdatesReplay(t *testing.T) {
+	text, err := os.ReadFile("testdata/WCNDMX.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := NewAWIPSProduct(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.ID = "WCNDMX202405212200"
+
+	pieces, err := ParseWatchUpdates(product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces) != 1 || pieces[0].ID != "TOA02502024" {
+		t.Fatalf("got watches %v, want TOA02502024", pieces)
+	}
+	if len(pieces[0].Updates) != 2 {
+		t.Fatalf("got %d updates, want the CAN and the EXA", len(pieces[0].Updates))
+	}
+
+	// The watch as the SPC issued it
+	issued := testTime(t, "2024-05-21T20:05:00Z")
+	expires := testTime(t, "2024-05-22T03:00:00Z")
+	watch := newWatchPiece("TO", 250, issued)
+	watch.Expires = &expires
+	watch.AddUpdate(WatchUpdate{
+		Product:  "WOU",
+		Issued:   issued,
+		Action:   "NEW",
+		Counties: []string{"IAC015", "IAC153", "IAC169"},
+		Expires:  &expires,
+	})
+
+	if err = watch.Merge(pieces[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		at   string
+		want string
+	}{
+		{"2024-05-21T20:00:00Z", ""},
+		{"2024-05-21T21:00:00Z", "IAC015,IAC153,IAC169"},
+		// The WCN drops Boone and Story and adds Hamilton and Hardin
+		{"2024-05-21T22:30:00Z", "IAC079,IAC083,IAC153"},
+		{"2024-05-22T03:00:00Z", ""},
+	}
+	for _, test := range tests {
+		if counties := strings.Join(watch.CountiesAt(testTime(t, test.at)), ","); counties != test.want {
+			t.Errorf("counties at %s are %q, want %q", test.at, counties, test.want)
+		}
+	}
+
+	if county := watch.Counties["IAC169"]; county == nil || county.Status != WatchCountyCancelled || county.Action != "CAN" {
+		t.Errorf("Story is %+v, want cancelled", county)
+	}
+	if county := watch.Counties["IAC079"]; county == nil || county.Status != WatchCountyActive || county.Action != "EXA" {
+		t.Errorf("Hamilton is %+v, want active", county)
+	}
+}
@@ -26,22 +26,12 @@ func ParseWMO(text string, issuedT time.Time) (WMO, error) {
 	layout := "021504"
 
 	issuedDecoded, err := time.Parse(layout, segments[2])
-
-	year := issuedT.Year()
-	month := issuedT.Month()
-	if issuedDecoded.Day() < issuedT.Day() {
-		month += 1
-		if month == 13 {
-			month = 1
-			year += 1
-		}
-	}
-
-	issued := time.Date(year, month, issuedDecoded.Day(), issuedDecoded.Hour(), issuedDecoded.Minute(), 0, 0, time.Now().UTC().Location())
 	if err != nil {
 		return WMO{}, errors.New("could not find WMO issued datetime")
 	}
 
+	issued := wmoTime(issuedDecoded.Day(), issuedDecoded.Hour(), issuedDecoded.Minute(), issuedT)
+
 	bbb := ""
 	if len(segments) > 3 {
 		bbb = segments[3]
@@ -55,3 +45,21 @@ func ParseWMO(text string, issuedT time.Time) (WMO, error) {
 		BBB:      bbb,
 	}, nil
 }
+
+// The WMO line only has the day so the month is the one that puts the time closest to the reference time
+func wmoTime(day int, hour int, minute int, reference time.Time) time.Time {
+	reference = reference.UTC()
+	var closest time.Time
+	for _, offset := range []time.Month{-1, 0, 1} {
+		first := time.Date(reference.Year(), reference.Month()+offset, 1, 0, 0, 0, 0, time.UTC)
+		t := time.Date(first.Year(), first.Month(), day, hour, minute, 0, 0, time.UTC)
+		// Skip months without the day
+		if t.Month() != first.Month() {
+			continue
+		}
+		if closest.IsZero() || t.Sub(reference).Abs() < closest.Sub(reference).Abs() {
+			closest = t
+		}
+	}
+	return closest
+}
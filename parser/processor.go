@@ -18,10 +18,6 @@ func Processor(text string) error {
 		return err
 	}
 
-	if product == nil {
-		return nil
-	}
-
 	record, err := marshal.SmartUnmarshal[[]struct {
 		Group string `json:"group"`
 	}](db.Surreal().Query(fmt.Sprintf("SELECT group FROM text_products WHERE group == '%s'", product.Group), map[string]string{}))
@@ -32,42 +28,132 @@ func Processor(text string) error {
 
 	product.ID = id
 
-	// Send products that need special treatment on their way
-	// Severe Watches
-	switch product.AWIPS.Product {
-	case "WWP":
-		fallthrough
-	case "SEL":
-		fallthrough
-	case "WOU":
-		watch, err := product.WatchProduct()
-		if err != nil {
-			return err
-		}
-		if watch.IsReady() {
-			return db.PushWatch(watch)
-		}
+	// Every product is kept, the handlers then relate whatever they parse from it back to it
+	if err = db.PushTextProduct(*product); err != nil {
+		return err
+	}
+
+	// Gridded and binary data have nothing to parse
+	if product.Family == parsers.HeaderFamilyOther {
 		return nil
-	case "PTS":
-		// product.PTSProduct()
 	}
-	if product.AWIPS.Product == "SWO" {
-		if product.AWIPS.WFO == "MCD" {
-			mcd, err := product.MCDProduct()
-			if err != nil {
-				return err
-			}
-			return db.PushMCD(mcd, product)
-		}
+
+	return parsers.RunHandlers(product)
+}
+
+// The priorities of the built in handlers. Products with their own parser are handled before VTEC
+const (
+	HandlerPriorityProduct = 100
+	HandlerPriorityVTEC    = 0
+)
+
+func init() {
+	handlers := []parsers.ProductHandler{
+		// Severe Watches
+		{
+			Name:      "watch",
+			AWIPS:     []string{"WWP*", "SEL*", "WOU*", "SAW*", "WWA*"},
+			Priority:  HandlerPriorityProduct,
+			Exclusive: true,
+			Handle: func(product *parsers.Product) error {
+				watch, err := product.WatchProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushWatch(watch)
+			},
+		},
+		// SPC convective and fire weather outlook points
+		{
+			Name:      "pts",
+			AWIPS:     []string{"PFW*", "PTS*"},
+			Priority:  HandlerPriorityProduct,
+			Exclusive: true,
+			Handle: func(product *parsers.Product) error {
+				outlooks, err := product.PTSProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushPTS(outlooks, product)
+			},
+		},
+		// SPC Mesoscale Discussions
+		{
+			Name:      "mcd",
+			AWIPS:     []string{"SWOMCD"},
+			Priority:  HandlerPriorityProduct,
+			Exclusive: true,
+			Handle: func(product *parsers.Product) error {
+				mcd, err := product.MCDProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushMCD(mcd, product)
+			},
+		},
+		// The WFOs' county updates to the severe watches also go through as VTEC
+		{
+			Name:     "wcn",
+			AWIPS:    []string{"WCN*"},
+			Priority: HandlerPriorityProduct,
+			Handle: func(product *parsers.Product) error {
+				watches, err := parsers.ParseWatchUpdates(product)
+				if err != nil {
+					return err
+				}
+				for _, watch := range watches {
+					if err = db.PushWatch(watch); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		// Local Storm Reports
+		{
+			Name:      "lsr",
+			AWIPS:     []string{"LSR*"},
+			Priority:  HandlerPriorityProduct,
+			Exclusive: true,
+			Handle: func(product *parsers.Product) error {
+				reports, err := product.LSRProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushLSR(reports, product)
+			},
+		},
+		// WPC Mesoscale Precipitation Discussions
+		{
+			Name:      "mpd",
+			AWIPS:     []string{"FFGMPD"},
+			Priority:  HandlerPriorityProduct,
+			Exclusive: true,
+			Handle: func(product *parsers.Product) error {
+				mpd, err := product.MPDProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushMPD(mpd, product)
+			},
+		},
+		{
+			Name:     "vtec",
+			Match:    (*parsers.Product).HasVTEC,
+			Priority: HandlerPriorityVTEC,
+			Handle: func(product *parsers.Product) error {
+				vtecProduct, err := product.VTECProduct()
+				if err != nil {
+					return err
+				}
+				return db.PushVTECProduct(vtecProduct)
+			},
+		},
 	}
 
-	if product.HasVTEC() {
-		vtecProduct, err := product.VTECProduct()
-		if err != nil {
-			return err
+	for _, handler := range handlers {
+		if err := parsers.RegisterHandler(handler); err != nil {
+			panic(err)
 		}
-		return db.PushVTECProduct(vtecProduct)
 	}
-
-	return nil
 }
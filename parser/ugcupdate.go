@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+)
+
+const (
+	DefaultUGCDataDir = "/nwws/ugc"
+	zonesURL          = "https://api.weather.gov/zones?include_geometry=true&type="
+)
+
+// The zone types to download from the NWS API. Each is saved as <type>.geojson
+var ugcZoneTypes = []string{"county", "public", "fire", "coastal", "offshore", "highseas"}
+
+/*
+Zone types the API may not serve. These are skipped if the download fails, the NWS high seas shapefile can be
+converted to GeoJSON and saved as highseas.geojson instead.
+*/
+var optionalUGCZoneTypes = map[string]bool{
+	"highseas": true,
+}
+
+// Where the baseline zones bundled with the parser are kept, relative to the parser module
+const ugcBaselineDir = "parsers/ugc"
+
+func ugcDataDir() string {
+	dir := os.Getenv("UGC_DATA_DIR")
+	if dir == "" {
+		dir = DefaultUGCDataDir
+	}
+	return dir
+}
+
+/*
+Load the UGC reference data if there is any, falling back to the baseline bundled with the parser.
+Parsing carries on without names and shapes if there is neither.
+*/
+func loadUGCReference() {
+	dir := ugcDataDir()
+	ref, err := parsers.LoadUGCReference(dir)
+	if err == nil && len(ref.Zones) > 0 {
+		parsers.SetUGCReference(ref)
+		log.Printf("Loaded %d UGC zones from %s\n", len(ref.Zones), dir)
+		return
+	}
+	if err != nil {
+		log.Printf("No UGC reference data loaded from %s: %s\n", dir, err.Error())
+	}
+
+	ref, err = parsers.LoadUGCBaseline()
+	if err != nil {
+		log.Printf("Could not load the bundled UGC zones: %s\n", err.Error())
+		return
+	}
+	if len(ref.Zones) == 0 {
+		log.Printf("No bundled UGC zones. Run --ugc-update to download them\n")
+		return
+	}
+	parsers.SetUGCReference(ref)
+	log.Printf("Loaded %d bundled UGC zones without shapes\n", len(ref.Zones))
+}
+
+// Write the zones without their shapes so they can be bundled with the parser
+func writeUGCBaseline(dir string, t string, zones []parsers.UGCZone) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.Marshal(zones)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, t+".json"), data, 0644)
+}
+
+/*
+RunUGCUpdate downloads the current zones and counties from the NWS API into the UGC data directory.
+With --baseline the bundled zones in parsers/ugc are refreshed too, ready to be committed.
+*/
+func RunUGCUpdate(args []string) error {
+	dir := ugcDataDir()
+	baseline := ""
+	for index := 0; index < len(args); index++ {
+		switch args[index] {
+		case "--baseline":
+			baseline = ugcBaselineDir
+			if index+1 < len(args) && args[index+1][0] != '-' {
+				index++
+				baseline = args[index]
+			}
+		default:
+			dir = args[index]
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, t := range ugcZoneTypes {
+		log.Printf("Downloading %s zones\n", t)
+
+		req, err := http.NewRequest("GET", zonesURL+t, nil)
+		if err != nil {
+			return err
+		}
+		// The NWS API requires a User-Agent
+		req.Header.Set("User-Agent", "NWWS-GO")
+		req.Header.Set("Accept", "application/geo+json")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			err = fmt.Errorf("failed to download %s zones: %s", t, res.Status)
+			if optionalUGCZoneTypes[t] {
+				log.Println(err.Error())
+				continue
+			}
+			return err
+		}
+
+		// Make sure what we got is usable before replacing the old file
+		path := filepath.Join(dir, t+".geojson")
+		tmp := path + ".tmp"
+		file, err := os.Create(tmp)
+		if err != nil {
+			res.Body.Close()
+			return err
+		}
+		_, err = io.Copy(file, res.Body)
+		res.Body.Close()
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		file, err = os.Open(tmp)
+		if err != nil {
+			return err
+		}
+		zones, err := parsers.ParseUGCGeoJSON(file, t)
+		file.Close()
+		if err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("downloaded %s zones are invalid: %s", t, err.Error())
+		}
+
+		if err = os.Rename(tmp, path); err != nil {
+			return err
+		}
+		log.Printf("Saved %d %s zones to %s\n", len(zones), t, path)
+
+		if baseline != "" {
+			if len(zones) == 0 {
+				return errors.New("no " + t + " zones to bundle")
+			}
+			if err = writeUGCBaseline(baseline, t, zones); err != nil {
+				return err
+			}
+			log.Printf("Bundled %d %s zones in %s\n", len(zones), t, baseline)
+		}
+	}
+
+	return nil
+}
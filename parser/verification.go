@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TheRangiCrew/NWWS-GO/parser/db"
+	"github.com/TheRangiCrew/NWWS-GO/parser/parsers"
+)
+
+// RunVerification prints the warning verification for --start to --end (exclusive) split by --period
+func RunVerification(args []string) error {
+	dateLayout := "2006-01-02"
+
+	end := time.Now().UTC().Truncate(day)
+	start := end.Add(-30 * day)
+	period := parsers.VerificationPeriodAll
+
+	argsLen := len(args)
+	for index := 0; index < argsLen; index++ {
+		arg := args[index]
+		if index+1 >= argsLen {
+			return fmt.Errorf("argument %s is missing a value", arg)
+		}
+		index++
+		value := args[index]
+
+		switch arg {
+		case "--start", "--end":
+			t, err := time.Parse(dateLayout, value)
+			if err != nil {
+				return fmt.Errorf("argument %s is not a valid date string", value)
+			}
+			if arg == "--start" {
+				start = t
+			} else {
+				end = t
+			}
+		case "--period":
+			switch value {
+			case parsers.VerificationPeriodAll, parsers.VerificationPeriodDay, parsers.VerificationPeriodMonth, parsers.VerificationPeriodYear:
+				period = value
+			default:
+				return fmt.Errorf("argument %s is not a valid period", value)
+			}
+		default:
+			return fmt.Errorf("unknown argument %s", arg)
+		}
+	}
+
+	if !start.Before(end) {
+		return fmt.Errorf("start %s is not before end %s", start.Format(dateLayout), end.Format(dateLayout))
+	}
+
+	results, err := db.QueryVerification(start, end, period)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Warning verification from %s to %s\n\n", start.Format(dateLayout), end.Format(dateLayout))
+	fmt.Print(parsers.VerificationReport(results))
+
+	return nil
+}